@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/urlquery/urlquery-cli/internal/cache"
+)
+
+var olderThanCache time.Duration
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the local report cache",
+	Long: `Completed reports are cached on disk when they are fetched, and read from the cache
+the next time instead of calling the API. Use --no-cache to bypass the cache, and
+--offline to only use cached reports (e.g. on an air-gapped machine).
+
+The cache is stored in the user cache directory ($XDG_CACHE_HOME/urlquery-cli/reports,
+~/.cache/urlquery-cli/reports by default), or in the directory set with 'config set cache_dir <dir>'.
+
+Usage:
+  urlquery-cli cache ls
+  urlquery-cli cache prune [--older-than 720h]
+  urlquery-cli cache clear`,
+	Annotations: map[string]string{annotationOffline: "true"},
+}
+
+var cacheLsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List cached reports",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := newReportCache().List()
+		if err != nil {
+			fmt.Println("Error reading cache:", err)
+			os.Exit(1)
+		}
+
+		if formatSet() || selectionSet() {
+			items := make([]any, len(entries))
+			for i, e := range entries {
+				items[i] = e
+			}
+			printResults(items)
+			return
+		}
+
+		var total int64
+		for _, e := range entries {
+			total += e.Size
+			fmt.Printf("%s  v%-3d %9s  %s\n", e.ReportID, e.Version, humanize.Bytes(uint64(e.Size)), humanize.Time(e.Modified))
+		}
+		fmt.Fprintf(os.Stderr, "%d cached reports (%s)\n", len(entries), humanize.Bytes(uint64(total)))
+	},
+}
+
+var cachePruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Remove old report versions and reports cached before --older-than",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var before time.Time
+		if olderThanCache > 0 {
+			before = time.Now().Add(-olderThanCache)
+		}
+
+		removed, err := newReportCache().Prune(before)
+		if err != nil {
+			fmt.Println("Error pruning cache:", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Removed %d cached reports\n", len(removed))
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached report",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		c := newReportCache()
+		if err := c.Clear(); err != nil {
+			fmt.Println("Error clearing cache:", err)
+			os.Exit(1)
+		}
+		fmt.Println("Cleared", c.Dir())
+	},
+}
+
+// newReportCache returns the report cache in the configured (or default) directory
+func newReportCache() *cache.Cache {
+	dir := viper.GetString("cache_dir")
+	if dir == "" {
+		var err error
+		if dir, err = cache.DefaultDir(); err != nil {
+			fmt.Println("Error finding cache directory:", err)
+			os.Exit(1)
+		}
+	}
+	return cache.New(dir)
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+var headersFlag []string
+
+// newTransport returns the HTTP transport configured with the proxy, CA bundle and
+// client certificate settings
+func newTransport() (*http.Transport, error) {
+	return api.NewTransport(api.TransportConfig{
+		Proxy:      viper.GetString("proxy"),
+		CACert:     viper.GetString("ca_cert"),
+		ClientCert: viper.GetString("client_cert"),
+		ClientKey:  viper.GetString("client_key"),
+	})
+}
+
+// extraHeaders returns the headers added to API requests: the headers section of the
+// config file, and the --header flags
+func extraHeaders() (map[string]string, error) {
+	headers := viper.GetStringMapString("headers")
+	for _, h := range headersFlag {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header '%s', expected 'Name: value'", h)
+		}
+		headers[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+type clientKey struct{}
+
+// withClient returns a context which gives the API client to the commands run with it,
+// e.g. a fake in tests
+func withClient(ctx context.Context, client api.Service) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// clientFor returns the API client of a command: the one in the command's context,
+// or a new client configured from the current settings
+func clientFor(cmd *cobra.Command) api.Service {
+	if ctx := cmd.Context(); ctx != nil {
+		if client, ok := ctx.Value(clientKey{}).(api.Service); ok {
+			return client
+		}
+	}
+	return newClient()
+}
+
+// newClient returns an API client configured from the current settings
+func newClient() api.Service {
+	offline := viper.GetBool("offline")
+
+	apikey := viper.GetString("apikey")
+	if apikey == "" && !offline {
+		fmt.Println("Error: API Key is required. Set it via 'config set apikey <value>' or use the --apikey flag.")
+		os.Exit(1)
+	}
+
+	retry := api.DefaultRetryPolicy
+	retry.MaxAttempts = viper.GetInt("retries") + 1
+
+	transport, err := newTransport()
+	if err != nil {
+		fmt.Println("Error configuring the connection:", err)
+		os.Exit(1)
+	}
+	headers, err := extraHeaders()
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+
+	opts := []api.OptionsClientFunc{
+		api.ApiKey(apikey),
+		api.Retry(retry),
+		api.Transport(transport),
+		api.Timeout(viper.GetDuration("http_timeout")),
+		api.Headers(headers),
+	}
+
+	if base := viper.GetString("apigw_base"); base != "" {
+		opts = append(opts, api.ApiGWBase(base))
+	}
+
+	if rate := viper.GetFloat64("rate"); rate > 0 {
+		opts = append(opts, api.RateLimit(rate, viper.GetInt("rate_burst")))
+	}
+
+	if !viper.GetBool("no_cache") {
+		opts = append(opts, api.ReportCache(newReportCache()))
+	} else if offline {
+		fmt.Println("Error: --offline needs the report cache, it can't be used with --no-cache")
+		os.Exit(1)
+	}
+	if viper.GetBool("refresh") {
+		if offline {
+			fmt.Println("Error: --refresh fetches reports from the API, it can't be used with --offline")
+			os.Exit(1)
+		}
+		opts = append(opts, api.Refresh())
+	}
+	if offline {
+		opts = append(opts, api.Offline())
+	}
+
+	client, err := api.NewClient(opts...)
+	if err != nil {
+		fmt.Println("Error creating API client:", err)
+		os.Exit(1)
+	}
+	return client
+}
@@ -0,0 +1,310 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/diff"
+	"github.com/urlquery/urlquery-cli/internal/integrity"
+)
+
+// fakeService serves reports from memory, and records the submissions
+type fakeService struct {
+	reports map[string]*api.Report
+	verdict string
+	hits    int
+
+	fail  map[string]error // Submit errors by URL
+	delay time.Duration    // Time taken by Submit
+
+	mu          sync.Mutex
+	submitted   []api.SubmitJob
+	inflight    int
+	maxInflight int // Most submissions at once
+}
+
+func (f *fakeService) Submit(ctx context.Context, submit api.SubmitJob) (*api.QueuedJob, error) {
+	f.mu.Lock()
+	f.submitted = append(f.submitted, submit)
+	id := len(f.submitted)
+	f.inflight++
+	f.maxInflight = max(f.maxInflight, f.inflight)
+	f.mu.Unlock()
+
+	time.Sleep(f.delay)
+
+	f.mu.Lock()
+	f.inflight--
+	f.mu.Unlock()
+
+	if err := f.fail[submit.Url]; err != nil {
+		return nil, err
+	}
+	return &api.QueuedJob{QueueID: fmt.Sprintf("q%d", id), Status: api.StatusQueued, Url: api.URL{Addr: submit.Url}}, nil
+}
+
+func (f *fakeService) QueueStatus(ctx context.Context, queue_id string) (*api.QueuedJob, error) {
+	return &api.QueuedJob{QueueID: queue_id, ReportID: "r1", Status: api.StatusDone}, nil
+}
+
+func (f *fakeService) WaitForReport(ctx context.Context, queue_id string, onStatus func(*api.QueuedJob)) (*api.QueuedJob, error) {
+	return f.QueueStatus(ctx, queue_id)
+}
+
+func (f *fakeService) GetReport(ctx context.Context, report_id string) (*api.Report, error) {
+	if r, ok := f.reports[report_id]; ok {
+		return r, nil
+	}
+	return nil, errors.New("not found")
+}
+
+func (f *fakeService) WriteScreenshot(ctx context.Context, report_id string, w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, "png")
+	return int64(n), err
+}
+
+func (f *fakeService) WriteDomainGraph(ctx context.Context, report_id string, w io.Writer) (int64, error) {
+	n, err := io.WriteString(w, "gif")
+	return int64(n), err
+}
+
+func (f *fakeService) WriteResource(ctx context.Context, report_id string, hash string, w io.Writer, offset int64) (int64, error) {
+	n, err := io.WriteString(w, "resource")
+	return int64(n), err
+}
+
+func (f *fakeService) Search(ctx context.Context, query string, limit int, offset int) (*api.SearchReportResponse, error) {
+	reply := &api.SearchReportResponse{Query: query, TotalHits: f.hits, Limit: limit, Offset: offset}
+	for i := offset; i < offset+limit && i < f.hits; i++ {
+		reply.Reports = append(reply.Reports, api.ReportOverview{ID: fmt.Sprintf("report-%d", i)})
+	}
+	return reply, nil
+}
+
+func (f *fakeService) CheckReputation(ctx context.Context, query string) (*api.ReputationResult, error) {
+	return &api.ReputationResult{Url: query, Verdict: f.verdict}, nil
+}
+
+// setFlag sets a flag of a command for the test, and restores it afterwards
+func setFlag(t *testing.T, cmd *cobra.Command, name, value string) {
+	t.Helper()
+	f := cmd.Flag(name)
+	if f == nil {
+		t.Fatalf("no flag %s", name)
+	}
+	old, changed := f.Value.String(), f.Changed
+	if err := f.Value.Set(value); err != nil {
+		t.Fatal(err)
+	}
+	f.Changed = true
+	t.Cleanup(func() {
+		f.Value.Set(old)
+		f.Changed = changed
+	})
+}
+
+// run runs a command with a fake API client, and returns its standard output
+func run(t *testing.T, client api.Service, cmd *cobra.Command, args ...string) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&buf, r)
+		close(done)
+	}()
+
+	cmd.SetContext(withClient(context.Background(), client))
+	cmd.Run(cmd, args)
+	w.Close()
+	<-done
+	return buf.String()
+}
+
+// newReport returns a report with an ID and a final URL
+func newReport(id, final string) *api.Report {
+	r := &api.Report{}
+	r.ID = id
+	r.Final.Url = api.URL{Schema: "https", Addr: final}
+	return r
+}
+
+const (
+	reportA = "82c4121d-d037-4d60-9f74-517bf00091ce"
+	reportB = "902d9135-12fe-4e75-95bb-a6d1e8c79ed1"
+)
+
+func TestReputationCmd(t *testing.T) {
+	fake := &fakeService{verdict: "malicious"}
+
+	var got api.ReputationResult
+	if err := json.Unmarshal([]byte(run(t, fake, reputationCmd, "http://example.com")), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Url != "http://example.com" || got.Verdict != "malicious" {
+		t.Errorf("reputation = %+v", got)
+	}
+}
+
+func TestSearchCmdAll(t *testing.T) {
+	setFlag(t, searchCmd, "all", "true")
+
+	lines := strings.Split(strings.TrimSpace(run(t, &fakeService{hits: 250}, searchCmd, "example.com")), "\n")
+	if len(lines) != 250 || !strings.Contains(lines[249], "report-249") {
+		t.Errorf("got %d results, last %s", len(lines), lines[len(lines)-1])
+	}
+}
+
+func TestSubmitCmdWait(t *testing.T) {
+	fake := &fakeService{reports: map[string]*api.Report{"r1": newReport("r1", "example.com/")}}
+	setFlag(t, submitCmd, "format", "json")
+	setFlag(t, submitCmd, "wait", "true")
+
+	var got api.Report
+	if err := json.Unmarshal([]byte(run(t, fake, submitCmd, "https://example.com")), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.ID != "r1" {
+		t.Errorf("report = %s", got.ID)
+	}
+	if len(fake.submitted) != 1 || fake.submitted[0].Url != "https://example.com" {
+		t.Errorf("submitted = %+v", fake.submitted)
+	}
+}
+
+func TestReportCmd(t *testing.T) {
+	dir := t.TempDir()
+	fake := &fakeService{reports: map[string]*api.Report{reportA: newReport(reportA, "example.com/")}}
+	setFlag(t, reportCmd, "output", dir)
+
+	run(t, fake, reportCmd, reportA, "report")
+	data, err := os.ReadFile(filepath.Join(dir, "report_"+reportA+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got api.Report
+	if err := json.Unmarshal(data, &got); err != nil || got.ID != reportA {
+		t.Errorf("report file = %s, %v", data, err)
+	}
+}
+
+func TestReportCmdDownload(t *testing.T) {
+	dir := t.TempDir()
+	fake := &fakeService{reports: map[string]*api.Report{}}
+	setFlag(t, reportCmd, "output", dir)
+
+	run(t, fake, reportCmd, reportA, "screenshot")
+	run(t, fake, reportCmd, reportA, "domain_graph")
+	sum := sha256.Sum256([]byte("resource"))
+	hash := hex.EncodeToString(sum[:])
+	if out := run(t, fake, reportCmd, reportA, "resource", hash); !strings.Contains(out, "bytes: 8") {
+		t.Errorf("resource output = %q", out)
+	}
+
+	for name, want := range map[string]string{
+		"screenshot_" + reportA + ".png":   "png",
+		"domain_graph_" + reportA + ".gif": "gif",
+		"resource_" + hash:                 "resource",
+	} {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q, %v", name, data, err)
+		}
+	}
+}
+
+func TestReportCmdQuarantine(t *testing.T) {
+	dir := t.TempDir()
+	fake := &fakeService{reports: map[string]*api.Report{}}
+	setFlag(t, reportCmd, "output", dir)
+	setFlag(t, reportCmd, "quarantine", "zip")
+
+	sum := sha256.Sum256([]byte("resource"))
+	hash := hex.EncodeToString(sum[:])
+	out := run(t, fake, reportCmd, reportA, "resource", hash)
+	if !strings.Contains(out, "quarantined: "+filepath.Join(dir, "resource_"+hash+".zip")) {
+		t.Errorf("resource output = %q", out)
+	}
+
+	for _, name := range []string{"resource_" + hash, "resource_" + hash + ".part"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s saved in clear", name)
+		}
+	}
+	for _, name := range []string{"resource_" + hash + ".zip", "resource_" + hash + ".zip.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestDownloadFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resource")
+	os.WriteFile(path+".part", []byte("res"), 0644) // Left by an earlier download
+
+	var perm os.FileMode
+	fetch := func(w io.Writer, offset int64) (int64, error) {
+		info, err := os.Stat(path + ".part")
+		if err != nil {
+			return 0, err
+		}
+		perm = info.Mode().Perm()
+		n, err := io.WriteString(w, "ource")
+		return int64(n), err
+	}
+	opts := downloadOptions{resume: true, store: func(part string, d integrity.Digest, size int64) (string, error) {
+		return path + ".stored", os.Rename(part, path+".stored")
+	}}
+
+	saved, _, size, err := downloadFile(path, fetch, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm != 0600 {
+		t.Errorf(".part permissions = %v", perm)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("stored file renamed to %s", path)
+	}
+	if data, _ := os.ReadFile(saved); string(data) != "resource" || size != 8 {
+		t.Errorf("%s = %q (%d bytes)", saved, data, size)
+	}
+}
+
+func TestReportDiffCmd(t *testing.T) {
+	fake := &fakeService{reports: map[string]*api.Report{
+		reportA: newReport(reportA, "example.com/"),
+		reportB: newReport(reportB, "example.com/login"),
+	}}
+	setFlag(t, reportDiffCmd, "format", "json")
+
+	var got diff.Diff
+	if err := json.Unmarshal([]byte(run(t, fake, reportDiffCmd, reportA, reportB)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.From.ReportID != reportA || got.To.ReportID != reportB || got.FinalURL == nil {
+		t.Errorf("diff = %+v", got)
+	}
+}
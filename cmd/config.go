@@ -1,13 +1,22 @@
 package cmd
 
 import (
-	"bytes"
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"gopkg.in/yaml.v2"
+	"github.com/urlquery/urlquery-cli/internal/config"
+	"github.com/urlquery/urlquery-cli/internal/notify"
+	"github.com/urlquery/urlquery-cli/internal/output"
+	"github.com/urlquery/urlquery-cli/internal/quarantine"
+	"github.com/urlquery/urlquery-cli/internal/secrets"
 )
 
 var configCmd = &cobra.Command{
@@ -25,35 +34,181 @@ Available keys:
   - output       Default directory to save downloaded reports or files
   - useragent    Default useragent to use for submissions
   - access       Set default access for submitted URL (public, restricted, private)
+  - retries      Number of times to retry throttled or failed API requests (default 3)
+  - rate         Maximum number of API requests per second (default 0, no limit)
+  - rate_burst   Number of API requests allowed in a burst when rate is set (default 1)
+  - format       Default output format: json, ndjson, yaml, csv, table or template=<file>
+  - quarantine   Quarantine downloaded resources: zip or defang
+  - cache_dir    Directory of the report cache (default: user cache directory)
+  - apigw_base   Custom API gateway base URL
+  - apikey_command  Command printing the API key, instead of apikey (e.g. pass show urlquery)
+  - secrets_file    Encrypted secrets file (default: ~/.urlquery-cli.secrets)
+  - proxy        Proxy URL for API requests (default from HTTPS_PROXY, HTTP_PROXY, NO_PROXY)
+  - ca_cert      PEM file of CA certificates to trust, e.g. of a TLS inspecting proxy
+  - client_cert  PEM file of a client certificate for mutual TLS
+  - client_key   PEM file of the client certificate key (default: client_cert)
+  - http_timeout Maximum time of an API request (default 30s)
+
+Extra headers for API requests, e.g. for an on-prem gateway, are set in the headers
+section of the config file, or with --header:
+
+  headers:
+    X-Tenant: soc
+
+The API key can be kept out of the config file: 'config set apikey --encrypt' stores it
+in the secrets file, encrypted with a passphrase (prompted, or URLQUERY_PASSPHRASE), and
+apikey_command runs a command such as a password manager to get it. The config file
+should only be readable by you, a warning is shown otherwise.
+
+Profiles are named sets of settings (apikey, apigw_base, access, useragent, proxy and
+TLS settings) for other API keys or environments, which override the settings above
+when used. Select one with --profile, URLQUERY_PROFILE or 'config profile use', and
+manage them with 'urlquery-cli config profile'. Profile 'default' uses the settings
+above only:
+
+  profile: team
+  profiles:
+    team:
+      apikey: team-key
+    staging:
+      apikey: staging-key
+      apigw_base: https://staging.example.com
+
+Notifications (submit --wait --notify, monitor run --notify) are configured by editing
+the notify section of the config file. By default, reports with urlquery alerts or a
+malicious reputation verdict are notified:
+
+  notify:
+    thresholds: {urlquery: 0, ids: 2, analyzer: 0}   # Notify when a count is above
+    verdicts: [malicious]
+    sinks:
+      - type: slack                                   # or teams
+        url: https://hooks.slack.com/services/...
+      - type: webhook
+        url: https://soar.example.com/hooks/urlquery
+        headers: {Authorization: Bearer abc123}
+        template: '{"title": {{json .Title}}, "report": {{json .ReportURL}}}'
+      - type: smtp
+        addr: smtp.example.com:587
+        from: urlquery@example.com
+        to: [soc@example.com]
+        username: urlquery
+        password: secret
+
+Use 'urlquery-cli config validate' to check the config file for errors, and
+'urlquery-cli doctor' to diagnose the connection to the API.
 
 Examples:
   urlquery-cli config show
+  urlquery-cli config validate
   urlquery-cli config set apikey abc123
   urlquery-cli config set output ./downloads
   urlquery-cli config set access public
+  urlquery-cli config set apikey team-key --profile team
 
   urlquery-cli config unset access
   urlquery-cli config unset apikey
 `,
 }
 
+var revealConfig bool
+var encryptConfig bool
+
 // Show command (config show)
 var configShowCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show current configuration",
+	Long: `Show the current configuration, with the profile in use and where each value
+comes from: a flag, an environment variable (URLQUERY_<KEY>), the profile, the config
+file or the default. Secrets such as the API key and notification passwords are masked,
+unless --reveal is given.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		file := loadConfigFile()
+		profile := activeProfile()
+
+		fmt.Println("Config file:", file.Path)
+		if profile != "" {
+			fmt.Printf("Profile: %s (%s)\n", profile, profileSource(cmd))
+		}
+		if profileErr != nil {
+			fmt.Println("Warning:", profileErr)
+		}
+
 		fmt.Println("Current Configuration:")
-		for _, key := range viper.AllKeys() {
-			fmt.Printf("  %s: %v\n", key, viper.Get(key))
+		keys := viper.AllKeys()
+		sort.Strings(keys)
+		for _, key := range keys {
+			if key == config.KeyProfile || strings.HasPrefix(key, config.KeyProfiles+".") {
+				continue
+			}
+			value := viper.Get(key)
+			if !revealConfig {
+				value = maskSecrets(key, value)
+			}
+			fmt.Printf("  %s: %v (%s)\n", key, value, configSource(cmd, file, profile, key))
+		}
+
+		if names := file.Profiles(); len(names) > 0 {
+			fmt.Println("Profiles:", strings.Join(names, ", "))
 		}
 	},
 }
 
+// configSource returns where the current value of a key comes from, following the
+// precedence of viper
+func configSource(cmd *cobra.Command, file *config.File, profile string, key string) string {
+	for _, name := range []string{key, strings.ReplaceAll(key, "_", "-")} {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			return "flag --" + name
+		}
+	}
+	env := "URLQUERY_" + strings.ToUpper(key)
+	if _, ok := os.LookupEnv(env); ok {
+		return "env " + env
+	}
+	if profile != "" {
+		if _, ok := file.Lookup(config.KeyProfiles + "." + profile + "." + key); ok {
+			return "profile " + profile
+		}
+	}
+	if _, ok := file.Lookup(key); ok {
+		return "file"
+	}
+	return "default"
+}
+
+// profileSource returns where the selected profile comes from
+func profileSource(cmd *cobra.Command) string {
+	if cmd.Flags().Changed("profile") {
+		return "flag --profile"
+	}
+	if _, ok := os.LookupEnv("URLQUERY_PROFILE"); ok {
+		return "env URLQUERY_PROFILE"
+	}
+	return "file"
+}
+
 var allowedConfigKeys = map[string]bool{
-	"apikey":    true,
-	"output":    true,
-	"access":    true,
-	"useragent": true,
+	"apikey":     true,
+	"output":     true,
+	"access":     true,
+	"useragent":  true,
+	"retries":    true,
+	"rate":       true,
+	"rate_burst": true,
+	"format":     true,
+	"quarantine": true,
+	"cache_dir":  true,
+	"apigw_base": true,
+
+	"apikey_command": true,
+	"secrets_file":   true,
+
+	"proxy":        true,
+	"ca_cert":      true,
+	"client_cert":  true,
+	"client_key":   true,
+	"http_timeout": true,
 }
 
 var allowedAccessValues = map[string]bool{
@@ -76,43 +231,90 @@ Available keys:
   - output       Default directory to save downloaded reports or files
   - useragent    Default User-Agent string for URL submissions
   - access       Default visibility for submitted URLs: public, restricted, or private
+  - retries      Number of times to retry throttled or failed API requests (default 3)
+  - rate         Maximum number of API requests per second (default 0, no limit)
+  - rate_burst   Number of API requests allowed in a burst when rate is set (default 1)
+  - format       Default output format: json, ndjson, yaml, csv, table or template=<file>
+  - quarantine   Quarantine downloaded resources: zip or defang
+  - cache_dir    Directory of the report cache (default: user cache directory)
+  - apigw_base   Custom API gateway base URL
+  - apikey_command  Command printing the API key, instead of apikey (e.g. pass show urlquery)
+  - secrets_file    Encrypted secrets file (default: ~/.urlquery-cli.secrets)
+  - proxy        Proxy URL for API requests (default from HTTPS_PROXY, HTTP_PROXY, NO_PROXY)
+  - ca_cert      PEM file of CA certificates to trust, e.g. of a TLS inspecting proxy
+  - client_cert  PEM file of a client certificate for mutual TLS
+  - client_key   PEM file of the client certificate key (default: client_cert)
+  - http_timeout Maximum time of an API request (default 30s)
+
+With --profile, the value is set in that profile instead (apikey, apikey_command,
+apigw_base, access, useragent, proxy, ca_cert, client_cert and client_key only). With --encrypt, the API key is stored in the
+secrets file, encrypted with a passphrase (prompted, or URLQUERY_PASSPHRASE), and the
+config file refers to it as secret:apikey. The API key is prompted when not given.
 
 Examples:
   urlquery-cli config set apikey abc123
+  urlquery-cli config set apikey --encrypt
+  urlquery-cli config set apikey_command "pass show urlquery"
   urlquery-cli config set output ./downloads
   urlquery-cli config set useragent "curl/7.81.0"
-  urlquery-cli config set access restricted`,
-	Args: cobra.ExactArgs(2),
+  urlquery-cli config set access restricted
+  urlquery-cli config set proxy http://proxy.example.com:3128
+  urlquery-cli config set ca_cert /etc/ssl/corp-ca.pem
+  urlquery-cli config set apigw_base https://staging.example.com --profile staging`,
+	Args: cobra.RangeArgs(1, 2),
 	Run: func(cmd *cobra.Command, args []string) {
 		key := args[0]
-		value := args[1]
 
 		if !allowedConfigKeys[key] {
 			fmt.Printf("Error: unsupported config key '%s'\n", key)
 			os.Exit(1)
 		}
 
+		if len(args) == 1 && !encryptConfig {
+			fmt.Println("Error: missing value, it can only be prompted with --encrypt")
+			os.Exit(1)
+		}
+		value := ""
+		if len(args) == 2 {
+			value = args[1]
+		}
+
 		if key == "access" && !allowedAccessValues[value] {
 			fmt.Printf("Error: invalid value for 'access'. Must be one of: public, restricted, private\n")
 			os.Exit(1)
 		}
 
-		configFile := viper.ConfigFileUsed()
-		if configFile == "" {
-			home, _ := os.UserHomeDir()
-			configFile = home + "/.urlquery-cli.yaml"
-			viper.SetConfigFile(configFile)
+		if key == "quarantine" && value != quarantine.ModeZip && value != quarantine.ModeDefang {
+			fmt.Printf("Error: invalid value for 'quarantine'. Must be one of: zip, defang\n")
+			os.Exit(1)
+		}
+
+		if value != "" {
+			if err := validateConfigValue(key, value); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
 		}
 
-		viper.Set(key, value)
+		file := loadConfigFile()
+		profile, inProfile := editedProfile(cmd, file, key)
+		if encryptConfig {
+			value = encryptSetting(key, profile, value)
+		}
 
-		// Save changes
-		if err := viper.WriteConfigAs(configFile); err != nil {
-			fmt.Println("Error saving config:", err)
-			os.Exit(1)
+		if inProfile {
+			values, _ := file.Profile(profile)
+			values[key] = value
+			file.SetProfile(profile, values)
+			key = profile + ": " + key
+		} else {
+			file.Set(key, value)
 		}
 
-		fmt.Printf("Config updated: %s = %s\n", key, value)
+		// Save changes
+		saveConfigFile(file)
+
+		fmt.Printf("Config updated: %s = %v\n", key, maskSecrets(key, value))
 	},
 }
 
@@ -125,6 +327,7 @@ This deletes the specified key from the config file (~/.urlquery-cli.yaml),
 which will cause the CLI to fall back to default behavior for that setting.
 
 You can use this to clear values like API keys, output directories, or access modes.
+With --profile, the value is removed from that profile instead.
 
 Examples:
 	urlquery-cli config unset apikey
@@ -133,37 +336,210 @@ Examples:
 	Run: func(cmd *cobra.Command, args []string) {
 		key := args[0]
 
-		configFile := viper.ConfigFileUsed()
-		if configFile == "" {
-			home, _ := os.UserHomeDir()
-			configFile = home + "/.urlquery-cli.yaml"
-			viper.SetConfigFile(configFile)
+		file := loadConfigFile()
+		removed := false
+		if profile, ok := editedProfile(cmd, file, key); ok {
+			values, _ := file.Profile(profile)
+			_, removed = values[key]
+			delete(values, key)
+			file.SetProfile(profile, values)
+			key = profile + ": " + key
+		} else {
+			removed = file.Unset(key)
 		}
 
-		if !viper.IsSet(key) {
+		if !removed {
 			fmt.Printf("Config key '%s' is not set.\n", key)
 			return
 		}
 
-		viper.Set(key, nil)  // Clear in memory
-		viper.ReadInConfig() // Load existing config
-		allSettings := viper.AllSettings()
-		delete(allSettings, key)
-
 		// Write updated config without the key
-		var buf bytes.Buffer
-		enc := yaml.NewEncoder(&buf)
-		defer enc.Close()
-		if err := enc.Encode(allSettings); err != nil {
-			fmt.Println("Error encoding config:", err)
-			os.Exit(1)
-		}
+		saveConfigFile(file)
 
-		if err := os.WriteFile(configFile, buf.Bytes(), 0644); err != nil {
-			fmt.Println("Error writing config:", err)
-			os.Exit(1)
+		fmt.Printf("Config key '%s' has been removed.\n", key)
+	},
+}
+
+// editedProfile returns the profile edited by 'config set' and 'config unset', when
+// --profile is given
+func editedProfile(cmd *cobra.Command, file *config.File, key string) (string, bool) {
+	if !cmd.Flags().Changed("profile") {
+		return "", false
+	}
+	profile := cmd.Flag("profile").Value.String()
+	if profile == config.DefaultProfile {
+		return "", false
+	}
+	if _, ok := file.Profile(profile); !ok {
+		fmt.Printf("Error: profile '%s' not found, add it with 'config profile add %s'\n", profile, profile)
+		os.Exit(1)
+	}
+	if !config.IsProfileKey(key) {
+		fmt.Printf("Error: '%s' can't be set in a profile, only: %s\n", key, strings.Join(config.ProfileKeys, ", "))
+		os.Exit(1)
+	}
+	return profile, true
+}
+
+// configFilePath returns the path of the config file, which may not exist yet
+func configFilePath() string {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path
+	}
+	if cfgFile != "" {
+		return cfgFile
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		fmt.Println("Error finding home directory:", err)
+		os.Exit(1)
+	}
+	return filepath.Join(home, ".urlquery-cli.yaml")
+}
+
+func loadConfigFile() *config.File {
+	file, err := config.Load(configFilePath())
+	if err != nil {
+		fmt.Println("Error reading config:", err)
+		os.Exit(1)
+	}
+	return file
+}
+
+func saveConfigFile(file *config.File) {
+	if err := file.Save(); err != nil {
+		fmt.Println("Error saving config:", err)
+		os.Exit(1)
+	}
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file for errors",
+	Long: `Check the config file for errors: YAML syntax, unknown keys, invalid values,
+profiles, the notify section and secrets referring to a missing secrets file.
+Exits with status 1 when the config file has problems.
+
+Use 'urlquery-cli doctor' to also check the connection to the API.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, problems := validateConfigFile()
+		if len(problems) == 0 {
+			fmt.Printf("Config file %s is valid.\n", file.Path)
+			return
 		}
 
-		fmt.Printf("Config key '%s' has been removed.\n", key)
+		fmt.Printf("Config file %s has problems:\n", file.Path)
+		for _, err := range problems {
+			fmt.Printf("  - %v\n", err)
+		}
+		os.Exit(1)
 	},
 }
+
+// validateConfigFile loads the config file, and returns its problems
+func validateConfigFile() (*config.File, []error) {
+	path := configFilePath()
+	file, err := config.Load(path)
+	if err != nil {
+		return &config.File{Path: path, Values: map[string]any{}}, []error{err}
+	}
+
+	known := []string{"summary", "tags", "debug", "no_cache", "offline", "notify", "headers"}
+	for key := range allowedConfigKeys {
+		known = append(known, key)
+	}
+	problems := file.Validate(known)
+
+	keys := make([]string, 0, len(file.Values))
+	for key := range file.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := validateConfigValue(key, file.Values[key]); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	for _, name := range file.Profiles() {
+		values, _ := file.Profile(name)
+		for _, key := range config.ProfileKeys {
+			if value, ok := values[key]; ok {
+				if err := validateConfigValue(key, value); err != nil {
+					problems = append(problems, fmt.Errorf("profile '%s': %w", name, err))
+				}
+			}
+		}
+	}
+
+	if _, ok := file.Values["notify"]; ok {
+		var cfg notify.Config
+		if err := viper.UnmarshalKey("notify", &cfg); err != nil {
+			problems = append(problems, fmt.Errorf("notify: %w", err))
+		} else if _, err := notify.New(cfg); err != nil {
+			problems = append(problems, fmt.Errorf("notify: %w", err))
+		}
+	}
+	return file, problems
+}
+
+// validateConfigValue checks the value of a top level or profile setting
+func validateConfigValue(key string, value any) error {
+	s := fmt.Sprint(value)
+	switch key {
+	case "access":
+		if !allowedAccessValues[s] {
+			return fmt.Errorf("invalid access '%s', must be one of: public, restricted, private", s)
+		}
+	case "quarantine":
+		if s != quarantine.ModeZip && s != quarantine.ModeDefang {
+			return fmt.Errorf("invalid quarantine '%s', must be one of: zip, defang", s)
+		}
+	case "format":
+		if _, err := output.New(s); err != nil {
+			return fmt.Errorf("invalid format: %w", err)
+		}
+	case "retries", "rate_burst":
+		if _, err := strconv.Atoi(s); err != nil {
+			return fmt.Errorf("%s must be a whole number, not '%s'", key, s)
+		}
+	case "rate":
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return fmt.Errorf("rate must be a number, not '%s'", s)
+		}
+	case "http_timeout":
+		if _, err := time.ParseDuration(s); err != nil {
+			return fmt.Errorf("invalid http_timeout '%s', expected a duration such as 30s", s)
+		}
+	case "proxy":
+		if u, err := url.Parse(s); err != nil || u.Host == "" {
+			return fmt.Errorf("invalid proxy URL '%s'", s)
+		}
+	case "apigw_base":
+		if u, err := url.Parse(s); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid apigw_base '%s', expected e.g. https://api.example.com", s)
+		}
+	case "ca_cert", "client_cert", "client_key":
+		if _, err := os.Stat(s); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	case "headers":
+		if _, ok := value.(map[string]any); !ok {
+			return fmt.Errorf("headers must map header names to values")
+		}
+	case "apikey":
+		if _, ok := secrets.Ref(s); ok && !secrets.Exists(secretsFilePath()) {
+			return fmt.Errorf("apikey refers to a secret, but the secrets file %s doesn't exist", secretsFilePath())
+		}
+	}
+	return nil
+}
+
+// errorsString joins errors in one line
+func errorsString(errs []error) string {
+	s := make([]string, len(errs))
+	for i, err := range errs {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "; ")
+}
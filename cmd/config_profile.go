@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/urlquery/urlquery-cli/internal/config"
+)
+
+var configProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage configuration profiles (API keys and environments)",
+	Long: `Manage named configuration profiles, e.g. a team API key or a staging environment.
+
+A profile overrides the apikey (or apikey_command), apigw_base, access, useragent and
+connection (proxy, ca_cert, client_cert, client_key) settings of the config file. The
+profile is selected with --profile, the URLQUERY_PROFILE environment variable, or by
+default with 'config profile use'. Flags and environment variables still override the
+profile settings.
+
+Examples:
+  urlquery-cli config profile add team apikey=team-key access=restricted
+  urlquery-cli config profile add staging apikey=staging-key apigw_base=https://staging.example.com
+  urlquery-cli config profile list
+  urlquery-cli config profile use team
+  urlquery-cli search "domain:example.com" --profile staging
+  urlquery-cli config profile use default
+  urlquery-cli config profile remove staging`,
+}
+
+var configProfileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the profiles, marking the one in use",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		file := loadConfigFile()
+		names := file.Profiles()
+		if len(names) == 0 {
+			fmt.Println("No profiles configured. Add one with 'config profile add <name> apikey=<key>'.")
+			return
+		}
+
+		active := activeProfile()
+		for _, name := range names {
+			mark := " "
+			if name == active {
+				mark = "*"
+			}
+			values, _ := file.Profile(name)
+			keys := make([]string, 0, len(values))
+			for key := range values {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			fmt.Printf("%s %-16s %s\n", mark, name, strings.Join(keys, ", "))
+		}
+	},
+}
+
+var configProfileUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Use a profile by default ('default' for no profile)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		file := loadConfigFile()
+
+		if name == config.DefaultProfile {
+			file.Unset(config.KeyProfile)
+			name = "none"
+		} else {
+			if _, ok := file.Profile(name); !ok {
+				fmt.Printf("Error: profile '%s' not found, see 'config profile list'\n", name)
+				os.Exit(1)
+			}
+			file.Set(config.KeyProfile, name)
+		}
+		saveConfigFile(file)
+
+		fmt.Printf("Default profile: %s\n", name)
+		if source := profileSource(cmd); source != "file" {
+			fmt.Printf("Note: the profile is currently selected by the %s\n", source)
+		}
+	},
+}
+
+var configProfileAddCmd = &cobra.Command{
+	Use:   "add <name> [key=value...]",
+	Short: "Add a profile, or update the settings of a profile",
+	Long: `Add a profile, or update the settings of an existing profile.
+
+Available keys:
+  - apikey       urlquery API key
+  - apikey_command  Command printing the API key (e.g. pass show urlquery-team)
+  - apigw_base   Custom API gateway base URL
+  - access       Default access for submitted URLs (public, restricted, private)
+  - useragent    Default useragent for submissions
+  - proxy        Proxy URL for API requests
+  - ca_cert      PEM file of CA certificates to trust
+  - client_cert  PEM file of a client certificate for mutual TLS
+  - client_key   PEM file of the client certificate key
+
+Examples:
+  urlquery-cli config profile add team apikey=team-key
+  urlquery-cli config profile add staging apikey=staging-key apigw_base=https://staging.example.com
+  urlquery-cli config profile add onprem apigw_base=https://urlquery.corp.example client_cert=/etc/urlquery/client.pem`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if err := config.ValidProfileName(name); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+
+		file := loadConfigFile()
+		values, exists := file.Profile(name)
+		if !exists {
+			values = map[string]any{}
+		}
+
+		for _, arg := range args[1:] {
+			key, value, ok := strings.Cut(arg, "=")
+			if !ok {
+				fmt.Printf("Error: invalid setting '%s', expected key=value\n", arg)
+				os.Exit(1)
+			}
+			if !config.IsProfileKey(key) {
+				fmt.Printf("Error: '%s' can't be set in a profile, only: %s\n", key, strings.Join(config.ProfileKeys, ", "))
+				os.Exit(1)
+			}
+			if err := validateConfigValue(key, value); err != nil {
+				fmt.Println("Error:", err)
+				os.Exit(1)
+			}
+			values[key] = value
+		}
+
+		file.SetProfile(name, values)
+		saveConfigFile(file)
+
+		if exists {
+			fmt.Printf("Profile '%s' updated\n", name)
+		} else {
+			fmt.Printf("Profile '%s' added, use it with --profile %s or 'config profile use %s'\n", name, name, name)
+		}
+	},
+}
+
+var configProfileRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove a profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		file := loadConfigFile()
+
+		if !file.RemoveProfile(name) {
+			fmt.Printf("Error: profile '%s' not found, see 'config profile list'\n", name)
+			os.Exit(1)
+		}
+		saveConfigFile(file)
+
+		fmt.Printf("Profile '%s' has been removed.\n", name)
+	},
+}
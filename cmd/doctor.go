@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/config"
+	"github.com/urlquery/urlquery-cli/internal/doctor"
+)
+
+// Maximum time of the network checks
+const doctorTimeout = 30 * time.Second
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the configuration and the connection to the API",
+	Long: `Check the setup of urlquery-cli, to find out why commands fail:
+
+  - the config file (syntax, unknown keys, values) and its permissions
+  - the profile and the API key (apikey_command, secrets file)
+  - the output directory is writable
+  - the proxy (proxy setting, or HTTPS_PROXY, NO_PROXY, ...), CA bundle and client
+    certificate
+  - DNS, connection and TLS certificate of the API gateway (apigw_base)
+  - the local clock against the API gateway
+  - the API key is accepted, with a search for one report
+
+The settings in use and where they come from (flag, env, profile, file) are listed,
+with secrets masked. Use --format json for a report to attach to support tickets.
+Exits with status 1 when a check fails.
+
+Examples:
+  urlquery-cli doctor
+  urlquery-cli doctor --profile staging
+  urlquery-cli doctor --format json > doctor.json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		report := doctor.NewReport(version)
+
+		file, problems := validateConfigFile()
+		report.Add(configFileCheck(file, problems))
+		report.Add(doctor.Permissions(file.Path))
+		report.Add(profileCheck(cmd))
+
+		apikey, err := resolveAPIKey(cmd)
+		if err != nil {
+			report.Add(doctor.Check{Name: "api key source", Status: doctor.StatusFail, Detail: err.Error()})
+		} else {
+			viper.Set("apikey", apikey)
+		}
+
+		dir := viper.GetString("output")
+		if dir == "" {
+			dir = "."
+		}
+		report.Add(doctor.OutputDir(dir))
+
+		base := viper.GetString("apigw_base")
+		if base == "" {
+			base = api.DefaultUrlqueryAPI
+		}
+		report.Add(networkChecks(cmd, base, apikey)...)
+
+		report.Settings = doctorSettings(cmd, file)
+
+		if formatSet() {
+			printResult(report)
+		} else {
+			printDoctorReport(report)
+		}
+		if !report.OK() {
+			os.Exit(1)
+		}
+	},
+}
+
+// configFileCheck reports if the config file exists and is valid
+func configFileCheck(file *config.File, problems []error) doctor.Check {
+	check := doctor.Check{Name: "config file", Status: doctor.StatusPass, Detail: file.Path}
+	if viper.ConfigFileUsed() == "" {
+		check.Status = doctor.StatusWarn
+		check.Detail = "no config file, create one with 'config set apikey <value>'"
+	} else if len(problems) > 0 {
+		check.Status = doctor.StatusFail
+		check.Detail = fmt.Sprintf("%s: %s", file.Path, errorsString(problems))
+	}
+	return check
+}
+
+// profileCheck reports the profile in use
+func profileCheck(cmd *cobra.Command) doctor.Check {
+	check := doctor.Check{Name: "profile", Status: doctor.StatusPass, Detail: "none"}
+	if profileErr != nil {
+		check.Status = doctor.StatusFail
+		check.Detail = profileErr.Error()
+	} else if profile := activeProfile(); profile != "" {
+		check.Detail = fmt.Sprintf("%s (%s)", profile, profileSource(cmd))
+	}
+	return check
+}
+
+// networkChecks checks the proxy, DNS, connection, TLS and clock of the API gateway,
+// and the API key
+func networkChecks(cmd *cobra.Command, base string, apikey string) []doctor.Check {
+	u, err := url.Parse(base)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return []doctor.Check{{Name: "api gateway", Status: doctor.StatusFail, Detail: fmt.Sprintf("invalid apigw_base '%s'", base)}}
+	}
+
+	// Connect like the API client, with the proxy, CA bundle and client certificate
+	transport, err := newTransport()
+	if err != nil {
+		return []doctor.Check{{Name: "connection settings", Status: doctor.StatusFail, Detail: err.Error()}}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+
+	checks := []doctor.Check{doctor.Proxy(base, transport.Proxy)}
+	if proxy, _ := transport.Proxy(&http.Request{URL: u}); proxy != nil {
+		checks = append(checks, doctor.Check{Name: "dns", Status: doctor.StatusSkip, Detail: "resolved by the proxy"})
+	} else {
+		checks = append(checks, doctor.DNS(ctx, u.Hostname()))
+	}
+
+	endpoint := doctor.Endpoint(ctx, &http.Client{Timeout: 15 * time.Second, Transport: transport}, base)
+	checks = append(checks, endpoint...)
+
+	if endpoint[0].Status == doctor.StatusFail {
+		return append(checks, doctor.Check{Name: "api key", Status: doctor.StatusSkip, Detail: "no connection"})
+	}
+	return append(checks, doctor.APIKey(ctx, apikey, func(ctx context.Context) error {
+		_, err := clientFor(cmd).Search(ctx, "urlquery.net", 1, 0)
+		return err
+	}))
+}
+
+// doctorSettings returns the settings which aren't defaults, with secrets masked
+func doctorSettings(cmd *cobra.Command, file *config.File) []doctor.Setting {
+	profile := activeProfile()
+	keys := viper.AllKeys()
+	sort.Strings(keys)
+
+	settings := []doctor.Setting{}
+	for _, key := range keys {
+		if key == config.KeyProfile || strings.HasPrefix(key, config.KeyProfiles+".") {
+			continue
+		}
+		source := configSource(cmd, file, profile, key)
+		if source == "default" {
+			continue
+		}
+		settings = append(settings, doctor.Setting{Key: key, Value: maskSecrets(key, viper.Get(key)), Source: source})
+	}
+	return settings
+}
+
+func printDoctorReport(report *doctor.Report) {
+	fmt.Printf("urlquery-cli %s (%s)\n\n", report.Version, report.OS)
+
+	labels := map[doctor.Status]string{
+		doctor.StatusPass: color.GreenString("PASS"),
+		doctor.StatusWarn: color.YellowString("WARN"),
+		doctor.StatusFail: color.RedString("FAIL"),
+		doctor.StatusSkip: color.HiBlackString("SKIP"),
+	}
+	failed := 0
+	for _, c := range report.Checks {
+		fmt.Printf("  %s  %-19s %s\n", labels[c.Status], c.Name, c.Detail)
+		if c.Status == doctor.StatusFail {
+			failed++
+		}
+	}
+
+	if len(report.Settings) > 0 {
+		fmt.Println("\nSettings:")
+		for _, s := range report.Settings {
+			fmt.Printf("  %s: %v (%s)\n", s.Key, s.Value, s.Source)
+		}
+	}
+
+	if failed == 1 {
+		fmt.Println("\n1 check failed")
+	} else if failed > 0 {
+		fmt.Printf("\n%d checks failed\n", failed)
+	} else {
+		fmt.Println("\nAll checks passed")
+	}
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/mattn/go-isatty"
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/integrity"
+)
+
+// fetchFunc streams an artifact to w, resuming at offset when supported
+type fetchFunc func(w io.Writer, offset int64) (int64, error)
+
+// downloadOptions controls how downloadFile writes an artifact
+type downloadOptions struct {
+	resume   bool                         // Resume from a .part file left by an interrupted download
+	progress bool                         // Show progress on stderr when it is a terminal
+	verify   func(integrity.Digest) error // Checks the content before it is kept (optional)
+
+	// Saves the verified .part file instead of renaming it (optional), e.g. to quarantine
+	// it. Returns the path of the saved file.
+	store func(part string, d integrity.Digest, size int64) (string, error)
+}
+
+// downloadFile streams an artifact to path atomically: the content is written to
+// <path>.part and renamed to path once it is complete and verified, so a failed
+// download never leaves a truncated file under the final name. With opts.store the
+// .part file is only readable by the owner, and is never renamed to path. Returns the
+// path of the saved file, and the digest and size of the content.
+func downloadFile(path string, fetch fetchFunc, opts downloadOptions) (string, integrity.Digest, int64, error) {
+	part := path + ".part"
+
+	flags := os.O_RDWR | os.O_CREATE
+	if !opts.resume {
+		flags |= os.O_TRUNC
+	}
+	perm := os.FileMode(0644)
+	if opts.store != nil {
+		perm = 0600
+	}
+	f, err := os.OpenFile(part, flags, perm)
+	if err != nil {
+		return "", integrity.Digest{}, 0, err
+	}
+	if opts.store != nil {
+		// A .part file left by a previous download may have been readable by others
+		if err := f.Chmod(perm); err != nil {
+			f.Close()
+			return "", integrity.Digest{}, 0, err
+		}
+	}
+
+	// The content of a previous attempt is hashed before resuming after it
+	hasher := integrity.NewHasher()
+	offset, err := io.Copy(hasher, f)
+	if err != nil {
+		f.Close()
+		return "", integrity.Digest{}, 0, err
+	}
+
+	w := &downloadWriter{file: f, hasher: hasher, name: path}
+	w.progress = opts.progress && isatty.IsTerminal(os.Stderr.Fd())
+
+	_, err = fetch(w, offset)
+	if errors.Is(err, api.ErrRangeIgnored) {
+		if err = w.reset(); err == nil {
+			_, err = fetch(w, 0)
+		}
+	}
+	w.done()
+
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		if !opts.resume {
+			os.Remove(part)
+		}
+		return "", integrity.Digest{}, 0, err
+	}
+
+	digest, size := w.hasher.Digest(), w.hasher.Size()
+	if opts.verify != nil {
+		if err := opts.verify(digest); err != nil {
+			os.Remove(part)
+			return "", digest, 0, err
+		}
+	}
+
+	if opts.store != nil {
+		saved, err := opts.store(part, digest, size)
+		if err != nil {
+			os.Remove(part)
+			return "", digest, 0, err
+		}
+		return saved, digest, size, nil
+	}
+	return path, digest, size, os.Rename(part, path)
+}
+
+// downloadWriter writes a download to a file while hashing it and showing progress
+type downloadWriter struct {
+	file   *os.File
+	hasher *integrity.Hasher
+	name   string
+
+	progress   bool
+	total      int64
+	lastUpdate time.Time
+}
+
+func (w *downloadWriter) Write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.hasher.Write(p[:n])
+
+	if w.progress && time.Since(w.lastUpdate) > 100*time.Millisecond {
+		w.lastUpdate = time.Now()
+		w.printProgress()
+	}
+	return n, err
+}
+
+// ExpectSize implements api.SizeReceiver
+func (w *downloadWriter) ExpectSize(offset, total int64) {
+	w.total = total
+}
+
+// reset discards what has been written, to restart the download
+func (w *downloadWriter) reset() error {
+	if err := w.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	w.hasher = integrity.NewHasher()
+	return nil
+}
+
+func (w *downloadWriter) printProgress() {
+	written := w.hasher.Size()
+	if w.total > 0 {
+		fmt.Fprintf(os.Stderr, "\r%s: %s / %s (%d%%)  ", w.name, humanize.Bytes(uint64(written)), humanize.Bytes(uint64(w.total)), written*100/w.total)
+	} else {
+		fmt.Fprintf(os.Stderr, "\r%s: %s  ", w.name, humanize.Bytes(uint64(written)))
+	}
+}
+
+func (w *downloadWriter) done() {
+	if w.progress && !w.lastUpdate.IsZero() {
+		w.printProgress()
+		fmt.Fprintln(os.Stderr)
+	}
+}
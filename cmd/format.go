@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/viper"
+	"github.com/urlquery/urlquery-cli/internal/filter"
+	"github.com/urlquery/urlquery-cli/internal/output"
+)
+
+var fieldsOutput []string
+var filterOutput string
+
+var compiledFilter *filter.Filter
+
+func init() {
+	// Make the summary template functions available to --format template=<file>
+	for name, fn := range templateFunctions {
+		output.TemplateFuncs[name] = fn
+	}
+}
+
+// formatSet reports if an output format was requested with --format (or config)
+func formatSet() bool {
+	return viper.GetString("format") != ""
+}
+
+// newFormatter returns the formatter selected with --format, or the command's default
+func newFormatter(defaultFormat string) output.Formatter {
+	spec := viper.GetString("format")
+	if spec == "" {
+		spec = defaultFormat
+	}
+
+	f, err := output.New(spec)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	return f
+}
+
+// selectionSet reports if results are filtered or reduced to selected fields
+func selectionSet() bool {
+	return filterOutput != "" || len(fieldsOutput) > 0
+}
+
+// selectResult applies --filter and --fields to a result. Returns false if the
+// result doesn't match the filter.
+func selectResult(v any) (any, bool) {
+	if filterOutput != "" {
+		if compiledFilter == nil {
+			f, err := filter.Parse(filterOutput)
+			if err != nil {
+				fmt.Println("Error: invalid --filter:", err)
+				os.Exit(1)
+			}
+			compiledFilter = f
+		}
+
+		ok, err := compiledFilter.Match(v)
+		if err != nil {
+			fmt.Println("Error applying filter:", err)
+			os.Exit(1)
+		}
+		if !ok {
+			return nil, false
+		}
+	}
+
+	if len(fieldsOutput) > 0 {
+		rec, err := filter.Select(v, fieldsOutput)
+		if err != nil {
+			fmt.Println("Error selecting fields:", err)
+			os.Exit(1)
+		}
+		return rec, true
+	}
+
+	return v, true
+}
+
+// printResult writes a command result to stdout in the selected format (default json),
+// unless it is excluded by --filter
+func printResult(v any) {
+	v, ok := selectResult(v)
+	if !ok {
+		return
+	}
+
+	if err := newFormatter("json").Format(os.Stdout, v); err != nil {
+		fmt.Println("Error formatting response:", err)
+		os.Exit(1)
+	}
+}
+
+// printResults writes a list of results, after applying --filter and --fields to each of them
+func printResults(items []any) {
+	list := output.List{}
+	for _, item := range items {
+		if v, ok := selectResult(item); ok {
+			list = append(list, v)
+		}
+	}
+
+	if err := newFormatter("json").Format(os.Stdout, list); err != nil {
+		fmt.Println("Error formatting response:", err)
+		os.Exit(1)
+	}
+}
+
+// writeResult adds a result to a stream, unless it is excluded by --filter
+func writeResult(s *output.Stream, v any) error {
+	v, ok := selectResult(v)
+	if !ok {
+		return nil
+	}
+	return s.Write(v)
+}
+
+// newResultStream returns a stream writing results to stdout in the selected format (default ndjson)
+func newResultStream() *output.Stream {
+	return output.NewStream(os.Stdout, newFormatter("ndjson"))
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/index"
+)
+
+var dirsLocal []string
+var limitLocal int
+var offsetLocal int
+var rebuildLocal bool
+
+var localCmd = &cobra.Command{
+	Use:   "local",
+	Short: "Search reports stored locally",
+	Long: `Searches the reports stored on this machine, without the API: the report cache, the
+reports downloaded to the output directory ('config set output <dir>'), and the
+directories given with --dir. Reports are indexed in the cache directory, and the
+index is updated with new and changed files before every search. Reports found with
+--dir stay indexed until their files are removed, or the index is rebuilt.
+
+Usage:
+  urlquery-cli local search <query>
+  urlquery-cli local index [--rebuild]`,
+	Annotations: map[string]string{annotationOffline: "true"},
+}
+
+var localSearchCmd = &cobra.Command{
+	Use:   "search <query>",
+	Short: "Search locally stored reports",
+	Long: `Searches locally stored reports with field:value terms, all of which must match.
+OR separates alternatives, and a term prefixed with - or NOT must not match.
+Values are matched without case, and * matches any characters.
+
+Fields:
+  domain       Domain or any of its subdomains
+  fqdn         Host name (alias: host)
+  ip           IP address or CIDR range (e.g. 192.0.2.0/24)
+  asn          AS number (e.g. 13335 or AS13335, alias: as)
+  country      Country code of the IP addresses
+  hash         md5, sha1, sha256 or sha512 of resources, files, scripts and certificates
+               (aliases: md5, sha1, sha256, sha512)
+  tag          Report, submission and alert tags (alias: tags)
+  alert        Text of the alert names (alias: alerts)
+  title        Text of the page title
+  url          Text of the URLs
+  date         Day, month or year (2025-06), compared (>=2025-06-01) or a range
+               (2025-06-01..2025-06-30)
+  id           Report ID (alias: report_id)
+
+A bare value matches any field. Results are the latest version of each report,
+newest first, written like the results of 'search'.
+
+Examples:
+  urlquery-cli local search example.com
+  urlquery-cli local search 'domain:example.com tag:phishing date:>=2025-06-01'
+  urlquery-cli local search 'ip:192.0.2.0/24 OR asn:AS13335' --format csv
+  urlquery-cli local search 'alert:"phishing kit" -tag:benign' --dir ./evidence`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if offsetLocal < 0 || limitLocal < 0 {
+			fmt.Println("Error: --offset and --limit can't be negative")
+			os.Exit(1)
+		}
+
+		query, err := index.ParseQuery(args[0])
+		if err != nil {
+			fmt.Println("Error parsing query:", err)
+			os.Exit(1)
+		}
+
+		start := time.Now()
+		idx, _ := updateLocalIndex(false)
+		reports := idx.Search(query)
+
+		results := &api.SearchReportResponse{
+			Query:     query.String(),
+			TotalHits: len(reports),
+			Limit:     limitLocal,
+			Offset:    offsetLocal,
+		}
+		reports = reports[min(offsetLocal, len(reports)):]
+		if limitLocal > 0 {
+			reports = reports[:min(limitLocal, len(reports))]
+		}
+		results.Reports = reports
+		results.TimeUsed = time.Since(start).Round(time.Millisecond).String()
+
+		if viper.GetBool("summary") {
+			fmt.Printf("🔍 Search Query: %s\n", results.Query)
+			fmt.Printf("Hits:    %d\n", results.TotalHits)
+			for _, v := range results.Reports {
+				printReportOverview(&v)
+			}
+			fmt.Println("")
+			return
+		}
+
+		if selectionSet() {
+			printResults(results.Items())
+			return
+		}
+		printResult(results)
+	},
+}
+
+var localIndexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Update the index of locally stored reports",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		idx, stats := updateLocalIndex(rebuildLocal)
+
+		msg := fmt.Sprintf("Indexed %d report files (%d new or changed, %d removed", idx.Len(), stats.Indexed, stats.Removed)
+		if stats.Failed > 0 {
+			msg += fmt.Sprintf(", %d unreadable", stats.Failed)
+		}
+		fmt.Println(msg + ")")
+	},
+}
+
+// updateLocalIndex indexes the new and changed local report files, and saves the index
+func updateLocalIndex(rebuild bool) (*index.Index, index.Stats) {
+	c := newReportCache()
+	path := filepath.Join(c.Dir(), "index.json")
+
+	idx := index.New()
+	if !rebuild {
+		var err error
+		if idx, err = index.Load(path); err != nil {
+			fmt.Println("Error reading index:", err)
+			os.Exit(1)
+		}
+	}
+
+	var paths []string
+	entries, err := c.List()
+	if err != nil {
+		fmt.Println("Error reading cache:", err)
+		os.Exit(1)
+	}
+	for _, e := range entries {
+		paths = append(paths, e.Path)
+	}
+
+	dirs := dirsLocal
+	if output := viper.GetString("output"); output != "" {
+		dirs = append(dirs, output)
+	}
+	for _, dir := range dirs {
+		found, err := index.FindReports(dir)
+		if err != nil {
+			fmt.Println("Error reading reports:", err)
+			os.Exit(1)
+		}
+		for _, p := range found {
+			if abs, err := filepath.Abs(p); err == nil {
+				p = abs
+			}
+			paths = append(paths, p)
+		}
+	}
+
+	stats := idx.Update(dedupe(paths))
+	if err := idx.Save(path); err != nil {
+		fmt.Println("Error writing index:", err)
+		os.Exit(1)
+	}
+	return idx, stats
+}
+
+// dedupe removes repeated paths, e.g. when --dir is also the output directory
+func dedupe(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	var list []string
+	for _, p := range paths {
+		if !seen[p] {
+			seen[p] = true
+			list = append(list, p)
+		}
+	}
+	return list
+}
@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/monitor"
+	"github.com/urlquery/urlquery-cli/internal/notify"
+)
+
+var stateMonitor string
+var onceMonitor bool
+var tickMonitor time.Duration
+var timeoutMonitor time.Duration
+var concurrencyMonitor int
+var notifyMonitor bool
+
+var monitorCmd = &cobra.Command{
+	Use:   "monitor",
+	Short: "Re-scan a watchlist of URLs and report changes",
+	Long: `Submits the URLs of a YAML watchlist periodically, waits for the reports, and compares
+every report with the previous report of the same URL. Changes of the verdict (URL
+reputation), final URL or alert counts are written as events.
+
+Watchlist:
+  defaults:
+    interval: 24h        # Go duration or days (7d), default 24h
+    access: private
+    tags: [lookalike]
+  urls:
+    - url: https://examp1e.com/login
+      interval: 6h
+      tags: [brand-x]
+    - url: https://example-login.net
+
+The state of every URL (pending submission, last report, verdict) is stored next to the
+watchlist (<watchlist>.state.json, or --state), so a restarted monitor resumes waiting
+for pending submissions instead of submitting them again.
+
+Usage:
+  urlquery-cli monitor run watchlist.yaml [--once]
+  urlquery-cli monitor status watchlist.yaml`,
+}
+
+var monitorRunCmd = &cobra.Command{
+	Use:   "run <watchlist>",
+	Short: "Scan the due URLs of the watchlist, until interrupted",
+	Long: `Scans the due URLs of the watchlist every --tick, until interrupted (or once with --once).
+The watchlist is read again every round, so it can be changed without a restart.
+
+Events are streamed as one JSON object per line (NDJSON, see --format):
+  submitted             A URL was submitted
+  checked               A report is done, with its verdict, alert counts and the diff
+                        with the previous report (see 'report diff')
+  verdict_changed       The reputation verdict of the URL changed
+  final_url_changed     The final URL changed
+  alert_count_changed   The urlquery, IDS or analyzer alert counts changed
+  error                 A submission or request failed
+
+With --notify, every report with detections (alert counts above the thresholds, or
+a malicious verdict) is sent to the sinks of the notify section of the config file.
+
+Examples:
+  urlquery-cli monitor run watchlist.yaml
+  urlquery-cli monitor run watchlist.yaml --once >> events.ndjson
+  urlquery-cli monitor run watchlist.yaml --filter 'type == "verdict_changed"'`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if !onceMonitor && tickMonitor < time.Second {
+			fmt.Println("Error: --tick must be at least 1s")
+			os.Exit(1)
+		}
+
+		path := args[0]
+		load := func() (*monitor.Watchlist, error) { return monitor.LoadWatchlist(path) }
+		w, err := load()
+		if err != nil {
+			fmt.Println("Error reading watchlist:", err)
+			os.Exit(1)
+		}
+
+		var nt *notify.Notifier
+		if notifyMonitor {
+			nt = newNotifier()
+		}
+
+		store := openMonitorStore(path)
+		stream := newResultStream()
+
+		// Notifications are sent in the background, as OnEvent holds up the other events
+		var notifying sync.WaitGroup
+
+		m := &monitor.Monitor{
+			Client: clientFor(cmd),
+			Store:  store,
+			Job: api.SubmitJob{
+				UserAgent: viper.GetString("useragent"),
+				Access:    viper.GetString("access"),
+			},
+			Timeout: timeoutMonitor,
+			Workers: concurrencyMonitor,
+			OnEvent: func(e monitor.Event) {
+				if err := writeResult(stream, e); err != nil {
+					fmt.Fprintln(os.Stderr, "Error formatting event:", err)
+				}
+				if nt != nil && e.Type == monitor.EventChecked {
+					n := notify.Notification{
+						Time:      e.Time,
+						Source:    "monitor",
+						URL:       e.URL,
+						ReportID:  e.ReportID,
+						ReportURL: notify.ReportURL(e.ReportID),
+						Verdict:   e.Verdict,
+						Alerts:    *e.Alerts,
+					}
+					notifying.Add(1)
+					go func() {
+						defer notifying.Done()
+						sendNotification(nt, n)
+					}()
+				}
+			},
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		fmt.Fprintf(os.Stderr, "Monitoring %d URLs (state: %s)\n", len(w.URLs), store.Path())
+		if onceMonitor {
+			m.RunOnce(ctx, w)
+		} else if err := m.Run(ctx, load, tickMonitor); err != nil {
+			fmt.Println("Error reading watchlist:", err)
+			os.Exit(1)
+		}
+		notifying.Wait()
+
+		if err := stream.Close(); err != nil {
+			fmt.Println("Error formatting response:", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var monitorStatusCmd = &cobra.Command{
+	Use:         "status <watchlist>",
+	Short:       "Show the state of the watched URLs",
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{annotationOffline: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		w, err := monitor.LoadWatchlist(args[0])
+		if err != nil {
+			fmt.Println("Error reading watchlist:", err)
+			os.Exit(1)
+		}
+
+		store := openMonitorStore(args[0])
+		items := make([]any, 0, len(w.URLs))
+		for _, e := range w.URLs {
+			items = append(items, store.Get(e.URL))
+		}
+
+		if formatSet() || selectionSet() {
+			printResults(items)
+			return
+		}
+
+		for _, item := range items {
+			st := item.(monitor.URLState)
+			status := "never checked"
+			switch {
+			case st.QueueID != "":
+				status = "pending since " + st.Submitted.Format(time.RFC3339)
+			case !st.Checked.IsZero():
+				status = fmt.Sprintf("checked %s, verdict %s, %d alerts, final URL %s",
+					st.Checked.Format(time.RFC3339), st.Verdict, st.Alerts.Urlquery, st.FinalURL)
+			}
+			fmt.Printf("%s\n   └─ %s\n", st.URL, status)
+			if st.LastError != "" {
+				fmt.Printf("   └─ Error: %s\n", st.LastError)
+			}
+		}
+	},
+}
+
+// openMonitorStore opens the state file given with --state, or the one of the watchlist
+func openMonitorStore(watchlist string) *monitor.Store {
+	path := stateMonitor
+	if path == "" {
+		path = strings.TrimSuffix(strings.TrimSuffix(watchlist, ".yaml"), ".yml") + ".state.json"
+	}
+
+	store, err := monitor.OpenStore(path)
+	if err != nil {
+		fmt.Println("Error reading monitor state:", err)
+		os.Exit(1)
+	}
+	return store
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/notify"
+)
+
+// Maximum time to send a notification to all sinks
+const notifyTimeout = time.Minute
+
+// newNotifier returns the notifier configured in the notify section of the config file
+func newNotifier() *notify.Notifier {
+	var cfg notify.Config
+	if err := viper.UnmarshalKey("notify", &cfg); err != nil {
+		fmt.Println("Error reading notify configuration:", err)
+		os.Exit(1)
+	}
+
+	// Webhooks go through the proxy, and trust the CA, configured for the API
+	transport, err := newTransport()
+	if err != nil {
+		fmt.Println("Error configuring the connection:", err)
+		os.Exit(1)
+	}
+	cfg.Transport = transport
+
+	nt, err := notify.New(cfg)
+	if err != nil {
+		fmt.Println("Error in notify configuration:", err)
+		os.Exit(1)
+	}
+	if len(nt.Sinks) == 0 {
+		fmt.Println("Error: --notify needs sinks in the notify section of the config file (see 'config --help')")
+		os.Exit(1)
+	}
+	return nt
+}
+
+// sendNotification notifies the sinks when the notification matches the rules.
+// Failures are reported on stderr, and don't stop the command.
+func sendNotification(nt *notify.Notifier, n notify.Notification) {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+
+	sent, err := nt.Notify(ctx, n)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error sending notification:", err)
+	}
+	if sent {
+		fmt.Fprintf(os.Stderr, "Notified detections for %s (%s)\n", n.URL, n.ReportURL)
+	}
+}
+
+// notifyReport checks the reputation of the submitted URL when the rules need the
+// verdict, and notifies the detections of the report
+func notifyReport(ctx context.Context, client api.Service, nt *notify.Notifier, submitted string, report *api.Report) {
+	verdict := ""
+	if nt.Rules.NeedsVerdict() {
+		rep, err := client.CheckReputation(ctx, submitted)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error checking reputation of %s: %v\n", submitted, err)
+		} else {
+			verdict = rep.Verdict
+		}
+	}
+
+	n := notify.FromReport("submit", report, verdict)
+	n.URL = submitted
+	sendNotification(nt, n)
+}
@@ -2,12 +2,19 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
-	"github.com/urlquery/urlquery-api-go"
-	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/bundle"
+	"github.com/urlquery/urlquery-cli/internal/har"
+	"github.com/urlquery/urlquery-cli/internal/integrity"
+	"github.com/urlquery/urlquery-cli/internal/ioc"
+	"github.com/urlquery/urlquery-cli/internal/misp"
+	"github.com/urlquery/urlquery-cli/internal/quarantine"
+	"github.com/urlquery/urlquery-cli/internal/stix"
 
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
@@ -15,7 +22,7 @@ import (
 )
 
 var reportCmd = &cobra.Command{
-	Use:   "report <report_id> <report|screenshot|domain_graph|resource> [hash]",
+	Use:   "report <report_id> <report|screenshot|domain_graph|resource|iocs|har|stix|misp|all> [hash]",
 	Short: "Fetch report details or download artifacts",
 	Long: `Retrieve data from a submitted URL scan by its Report ID.
 
@@ -24,14 +31,35 @@ You can download the full JSON report, screenshot, domain graph visualization, o
   - screenshot    Screenshot of the loaded URL
   - domain_graph  Visual representation of domain relationships
   - resource      Specific resource from the scan (hash)
+  - iocs          Indicators (URLs, domains, IPs, ASNs, hashes, certificate fingerprints) with their alerts
+  - har           HTTP transactions as a HAR 1.2 archive (report_<id>.har)
+  - stix          Observables, indicators and sightings as a STIX 2.1 bundle (report_<id>.stix.json)
+  - misp          Indicators as a MISP event, for import in MISP (report_<id>.misp.json)
+  - all           Report, screenshot, domain graph and every resource into <output>/<report_id>/,
+                  with a manifest.json (names, sizes and sha256). --pack zip|tar.gz packs the bundle.
+
+Downloaded resources are verified against the requested hash and the hashes listed in the report,
+and are not saved when they don't match. Use 'report verify <dir>' to re-check a downloaded bundle.
+
+Use 'report diff <report_id_a> <report_id_b>' to compare two reports, e.g. two scans of the same URL.
+
+Resources are live samples. Use --quarantine zip to store them in a zip encrypted with the password
+'infected', or --quarantine defang to rename them with a defanged extension (e.g. .exe_). Quarantined
+files are read-only and come with a sidecar <file>.json describing their origin and analyzer verdicts.
 
 All downloaded files are saved in the output directory (default: current directory, or set via 'config set output <calue>' use --output).
+When --format, --fields or --filter is given, the report is written to stdout instead of to a file.
 
 Usage:
   urlquery-cli report <report_id> report
   urlquery-cli report <report_id> screenshot
   urlquery-cli report <report_id> domain_graph
   urlquery-cli report <report_id> resource <hash>
+  urlquery-cli report <report_id> iocs [--format csv|json]
+  urlquery-cli report <report_id> har
+  urlquery-cli report <report_id> stix
+  urlquery-cli report <report_id> misp
+  urlquery-cli report <report_id> all [--pack zip|tar.gz]
 
 Examples:
   urlquery-cli report 82c4121d-d037-4d60-9f74-517bf00091ce report
@@ -52,9 +80,13 @@ Examples:
 			os.Exit(1)
 		}
 
-		apikey := viper.GetString("apikey")
+		if mode := viper.GetString("quarantine"); mode != "" && mode != quarantine.ModeZip && mode != quarantine.ModeDefang {
+			fmt.Printf("Error: unknown quarantine mode '%s' (available: zip, defang)\n", mode)
+			os.Exit(1)
+		}
 
-		client := urlquery.NewClient(apikey)
+		ctx := cmd.Context()
+		client := clientFor(cmd)
 
 		// Handle report data
 		validActions := map[string]bool{
@@ -62,13 +94,18 @@ Examples:
 			"screenshot":   true,
 			"domain_graph": true,
 			"resource":     true,
+			"iocs":         true,
+			"har":          true,
+			"stix":         true,
+			"misp":         true,
+			"all":          true,
 		}
 		if validActions[action] {
 			// fmt.Printf("Fetching %s for Report: %s\n", action, report_id)
 
 			// Fetch Report
 			if action == "report" {
-				report, err := client.GetReport(report_id)
+				report, err := client.GetReport(ctx, report_id)
 				if err != nil {
 					fmt.Println("Failed", err)
 					os.Exit(1)
@@ -76,15 +113,12 @@ Examples:
 
 				summary := viper.GetBool("summary")
 				if summary {
-					var parsed api.Report
-					if err := json.Unmarshal(report.Bytes(), &parsed); err != nil {
-						fmt.Println("Error parsing report:", err)
-						os.Exit(1)
-					}
-
-					tmp := SummarizeReport(&parsed)
+					tmp := SummarizeReport(report)
 					fmt.Println(tmp)
 
+				} else if formatSet() || selectionSet() {
+					printResult(report)
+
 				} else {
 					reportFilename := fmt.Sprintf("report_%s.json", report_id)
 					filePath := filepath.Join(output_directory, reportFilename)
@@ -100,26 +134,129 @@ Examples:
 
 			}
 
+			// Extract indicators
+			if action == "iocs" {
+				report, err := client.GetReport(ctx, report_id)
+				if err != nil {
+					fmt.Println("Failed", err)
+					os.Exit(1)
+				}
+
+				printIOCs(ioc.Extract(report))
+			}
+
+			// Export HTTP transactions as HAR
+			if action == "har" {
+				report, err := client.GetReport(ctx, report_id)
+				if err != nil {
+					fmt.Println("Failed", err)
+					os.Exit(1)
+				}
+
+				data, err := json.MarshalIndent(har.FromReport(report, version), "", "  ")
+				if err != nil {
+					fmt.Println("Error formatting HAR:", err)
+					os.Exit(1)
+				}
+
+				harFilename := fmt.Sprintf("report_%s.har", report_id)
+				if err := os.WriteFile(filepath.Join(output_directory, harFilename), data, 0644); err != nil {
+					fmt.Println("Failed to write file:", err)
+					os.Exit(1)
+				}
+			}
+
+			// Export as a STIX 2.1 bundle
+			if action == "stix" {
+				report, err := client.GetReport(ctx, report_id)
+				if err != nil {
+					fmt.Println("Failed", err)
+					os.Exit(1)
+				}
+
+				data, err := json.MarshalIndent(stix.FromReport(report), "", "  ")
+				if err != nil {
+					fmt.Println("Error formatting STIX bundle:", err)
+					os.Exit(1)
+				}
+
+				stixFilename := fmt.Sprintf("report_%s.stix.json", report_id)
+				if err := os.WriteFile(filepath.Join(output_directory, stixFilename), data, 0644); err != nil {
+					fmt.Println("Failed to write file:", err)
+					os.Exit(1)
+				}
+			}
+
+			// Export as a MISP event
+			if action == "misp" {
+				report, err := client.GetReport(ctx, report_id)
+				if err != nil {
+					fmt.Println("Failed", err)
+					os.Exit(1)
+				}
+
+				data, err := json.MarshalIndent(misp.Wrapper{Event: misp.FromReport(report)}, "", "  ")
+				if err != nil {
+					fmt.Println("Error formatting MISP event:", err)
+					os.Exit(1)
+				}
+
+				mispFilename := fmt.Sprintf("report_%s.misp.json", report_id)
+				if err := os.WriteFile(filepath.Join(output_directory, mispFilename), data, 0644); err != nil {
+					fmt.Println("Failed to write file:", err)
+					os.Exit(1)
+				}
+			}
+
+			// Download everything into a bundle directory
+			if action == "all" {
+				if packReport != "" && packReport != bundle.FormatZip && packReport != bundle.FormatTarGz {
+					fmt.Printf("Error: unknown archive format '%s' (available: zip, tar.gz)\n", packReport)
+					os.Exit(1)
+				}
+
+				dir, failed, err := downloadBundle(ctx, client, report_id, output_directory, concurrencyReport)
+				if err != nil {
+					fmt.Println("Failed", err)
+					os.Exit(1)
+				}
+
+				if packReport != "" {
+					archive, err := bundle.Pack(dir, packReport)
+					if err != nil {
+						fmt.Println("Error packing bundle:", err)
+						os.Exit(1)
+					}
+					fmt.Println(archive)
+				} else {
+					fmt.Println(dir)
+				}
+
+				if failed > 0 {
+					os.Exit(1)
+				}
+			}
+
 			// Fetch Domain Graph
 			if action == "domain_graph" {
 				domain_graph_filename := fmt.Sprintf("domain_graph_%s.gif", report_id)
 
-				data_domain_graph, _ := client.GetDomainGraph(report_id)
-
-				f, _ := os.OpenFile(output_directory+domain_graph_filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-				f.Write(data_domain_graph)
-				f.Close()
+				fetch := func(w io.Writer, offset int64) (int64, error) { return client.WriteDomainGraph(ctx, report_id, w) }
+				if _, _, _, err := downloadFile(output_directory+domain_graph_filename, fetch, downloadOptions{progress: true}); err != nil {
+					fmt.Println("Error downloading domain graph:", err)
+					os.Exit(1)
+				}
 			}
 
 			// Fetch Screenshot
 			if action == "screenshot" {
 				screenshot_filename := fmt.Sprintf("screenshot_%s.png", report_id)
 
-				data_screenshot, _ := client.GetScreenshot(report_id)
-
-				f, _ := os.OpenFile(screenshot_filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-				f.Write(data_screenshot)
-				f.Close()
+				fetch := func(w io.Writer, offset int64) (int64, error) { return client.WriteScreenshot(ctx, report_id, w) }
+				if _, _, _, err := downloadFile(output_directory+screenshot_filename, fetch, downloadOptions{progress: true}); err != nil {
+					fmt.Println("Error downloading screenshot:", err)
+					os.Exit(1)
+				}
 			}
 
 			// Handle downloading a resource
@@ -129,19 +266,52 @@ Examples:
 					os.Exit(1)
 				}
 				hash := args[2]
+				if !validResourceHash(hash) {
+					fmt.Printf("Error: '%s' is not a valid md5, sha1 or sha256 hash.\n", hash)
+					os.Exit(1)
+				}
 
 				resource_filename := fmt.Sprintf("resource_%s", hash)
 
-				data_resource, err := client.GetResource(report_id, hash)
+				// The report lists the hashes the resource is expected to have
+				report, err := client.GetReport(ctx, report_id)
 				if err != nil {
-					fmt.Print("Error downloading resource:", err)
-					return
+					fmt.Fprintln(os.Stderr, "Warning: could not fetch the report, only the requested hash is verified:", err)
+					report = nil
 				}
-				fmt.Println("bytes:", len(data_resource))
 
-				f, _ := os.OpenFile(output_directory+resource_filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-				f.Write(data_resource)
-				f.Close()
+				// Interrupted downloads are resumed (the name is unique to the content)
+				fetch := func(w io.Writer, offset int64) (int64, error) {
+					return client.WriteResource(ctx, report_id, hash, w, offset)
+				}
+				opts := downloadOptions{
+					resume:   true,
+					progress: true,
+					verify:   func(d integrity.Digest) error { return verifyResource(report, hash, d) },
+				}
+
+				// Quarantined resources are never saved in clear under their final name
+				mode := viper.GetString("quarantine")
+				if mode != "" {
+					opts.store = func(part string, d integrity.Digest, size int64) (string, error) {
+						return quarantine.Apply(part, output_directory+resource_filename, mode, quarantine.NewSidecar(report_id, report, d, size))
+					}
+				}
+
+				saved, _, size, err := downloadFile(output_directory+resource_filename, fetch, opts)
+				var mismatch *integrity.MismatchError
+				if errors.As(err, &mismatch) {
+					fmt.Println("Error: downloaded resource failed verification, not saved:", err)
+					os.Exit(1)
+				}
+				if err != nil {
+					fmt.Println("Error downloading resource:", err)
+					os.Exit(1)
+				}
+				fmt.Println("bytes:", size)
+				if mode != "" {
+					fmt.Println("quarantined:", saved)
+				}
 
 				return
 			}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/bundle"
+	"github.com/urlquery/urlquery-cli/internal/integrity"
+	"github.com/urlquery/urlquery-cli/internal/quarantine"
+)
+
+var packReport string
+var concurrencyReport int
+
+// artifact is a file of a report bundle
+type artifact struct {
+	name  string
+	fetch fetchFunc
+	opts  downloadOptions
+}
+
+// downloadBundle downloads the report, screenshot, domain graph and every resource
+// of a report into <output>/<report_id>, and writes the manifest. Failed downloads
+// are listed in the manifest with their error. Returns the bundle directory and
+// the number of failed downloads.
+func downloadBundle(ctx context.Context, client api.Service, reportID, outputDir string, workers int) (string, int, error) {
+	report, err := client.GetReport(ctx, reportID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	dir := filepath.Join(outputDir, reportID)
+	if err := os.MkdirAll(filepath.Join(dir, "resources"), 0755); err != nil {
+		return "", 0, err
+	}
+
+	manifest := bundle.NewManifest(reportID)
+	data := report.Bytes()
+	if err := os.WriteFile(filepath.Join(dir, "report.json"), data, 0644); err != nil {
+		return "", 0, err
+	}
+	manifest.Files = append(manifest.Files, bundle.NewFile("report.json", data))
+
+	artifacts := []artifact{
+		{name: "screenshot.png", fetch: func(w io.Writer, offset int64) (int64, error) { return client.WriteScreenshot(ctx, reportID, w) }},
+		{name: "domain_graph.gif", fetch: func(w io.Writer, offset int64) (int64, error) { return client.WriteDomainGraph(ctx, reportID, w) }},
+	}
+	mode := viper.GetString("quarantine")
+	for _, hash := range resourceHashes(report) {
+		hash := hash
+		a := artifact{
+			name: "resources/" + hash,
+			fetch: func(w io.Writer, offset int64) (int64, error) {
+				return client.WriteResource(ctx, reportID, hash, w, offset)
+			},
+			opts: downloadOptions{
+				resume: true,
+				verify: func(d integrity.Digest) error { return verifyResource(report, hash, d) },
+			},
+		}
+		if mode != "" {
+			path := filepath.Join(dir, filepath.FromSlash(a.name))
+			a.opts.store = func(part string, d integrity.Digest, size int64) (string, error) {
+				saved, err := quarantine.Apply(part, path, mode, quarantine.NewSidecar(reportID, report, d, size))
+				if err != nil {
+					return "", fmt.Errorf("quarantine: %w", err)
+				}
+				return saved, nil
+			}
+		}
+		artifacts = append(artifacts, a)
+	}
+
+	failed := 0
+	for _, f := range downloadArtifacts(dir, artifacts, workers) {
+		if f.Error != "" {
+			failed++
+			fmt.Fprintf(os.Stderr, "Failed to download %s: %s\n", f.Name, f.Error)
+		}
+		manifest.Files = append(manifest.Files, f)
+	}
+
+	return dir, failed, manifest.Write(dir)
+}
+
+// downloadArtifacts downloads the artifacts into dir using a fixed number of workers.
+// Returns the downloaded files, including the sidecars of quarantined files.
+func downloadArtifacts(dir string, artifacts []artifact, workers int) []bundle.File {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan artifact)
+	results := make(chan []bundle.File)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range jobs {
+				results <- downloadArtifact(dir, a)
+			}
+		}()
+	}
+
+	go func() {
+		for _, a := range artifacts {
+			jobs <- a
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	var files []bundle.File
+	for f := range results {
+		files = append(files, f...)
+	}
+	return files
+}
+
+func downloadArtifact(dir string, a artifact) []bundle.File {
+	path := filepath.Join(dir, filepath.FromSlash(a.name))
+	saved, digest, size, err := downloadFile(path, a.fetch, a.opts)
+	if err != nil {
+		return []bundle.File{{Name: a.name, Error: err.Error()}}
+	}
+	if a.opts.store == nil {
+		return []bundle.File{{Name: a.name, Size: size, SHA256: digest.SHA256}}
+	}
+
+	var files []bundle.File
+	for _, p := range []string{saved, saved + ".json"} {
+		f, err := bundle.HashFile(dir, p)
+		if err != nil {
+			f.Error = err.Error()
+		}
+		files = append(files, f)
+	}
+	return files
+}
+
+// resourceHashes returns the unique sha256 hashes of the responses and files of a report
+func resourceHashes(r *api.Report) []string {
+	var hashes []string
+	seen := make(map[string]bool)
+
+	add := func(hash string) {
+		hash = strings.ToLower(hash)
+		if _, err := hex.DecodeString(hash); err != nil || hash == "" || seen[hash] {
+			return
+		}
+		seen[hash] = true
+		hashes = append(hashes, hash)
+	}
+
+	for _, tx := range r.HttpTransactions {
+		add(tx.Response.Content.Sha256)
+	}
+	for _, f := range r.FileDetections {
+		add(f.Sha256)
+	}
+	return hashes
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/urlquery/urlquery-cli/internal/diff"
+)
+
+var reportDiffCmd = &cobra.Command{
+	Use:   "diff <report_id_a> <report_id_b>",
+	Short: "Compare two reports, e.g. two scans of the same URL",
+	Long: `Shows what changed from the first report to the second: the final URL and title,
+the domains of the domain summary, the IPs and ASNs contacted, the resources loaded
+(by sha256), the alerts of each sensor, and the certificates of the hosts found in
+both reports.
+
+The changes are written as a summary, or as a JSON diff with --format json (or any
+other format).
+
+Examples:
+  urlquery-cli report diff 82c4121d-d037-4d60-9f74-517bf00091ce 902d9135-12fe-4e75-95bb-a6d1e8c79ed1
+  urlquery-cli report diff <report_id_a> <report_id_b> --format json > diff.json`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		client := clientFor(cmd)
+
+		a, err := client.GetReport(cmd.Context(), args[0])
+		if err != nil {
+			fmt.Println("Failed", err)
+			os.Exit(1)
+		}
+		b, err := client.GetReport(cmd.Context(), args[1])
+		if err != nil {
+			fmt.Println("Failed", err)
+			os.Exit(1)
+		}
+
+		d := diff.Compare(a, b)
+		if formatSet() || selectionSet() {
+			printResult(d)
+			return
+		}
+		fmt.Println(SummarizeDiff(d))
+	},
+}
@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/urlquery/urlquery-cli/internal/ioc"
+)
+
+// printIOCs writes the indicators as text, or in the format selected with --format
+func printIOCs(indicators []ioc.Indicator) {
+	if formatSet() || selectionSet() {
+		items := make([]any, len(indicators))
+		for i, ind := range indicators {
+			items[i] = ind
+		}
+		printResults(items)
+		return
+	}
+
+	for _, ind := range indicators {
+		fmt.Printf("%-12s %s\n", ind.Type, ind.Value)
+		for _, alert := range ind.Alerts {
+			fmt.Printf("   └─ %s\n", alert)
+		}
+	}
+}
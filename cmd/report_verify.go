@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/bundle"
+	"github.com/urlquery/urlquery-cli/internal/integrity"
+)
+
+var reportVerifyCmd = &cobra.Command{
+	Use:   "verify <dir>",
+	Short: "Verify a downloaded report bundle against its manifest",
+	Long: `Re-check the files of a bundle downloaded with 'report <report_id> all' against its manifest.json.
+
+Every file must have the size and sha256 listed in the manifest, and resources must
+match the hash they are named after. Exits with status 1 if a file is missing or altered.
+
+Example:
+  urlquery-cli report verify ./82c4121d-d037-4d60-9f74-517bf00091ce`,
+	Args:        cobra.ExactArgs(1),
+	Annotations: map[string]string{annotationOffline: "true"},
+	Run: func(cmd *cobra.Command, args []string) {
+		checks, err := bundle.Verify(args[0])
+		if err != nil {
+			fmt.Println("Error reading bundle:", err)
+			os.Exit(1)
+		}
+
+		failed := 0
+		for _, c := range checks {
+			if c.Status == bundle.StatusMissing || c.Status == bundle.StatusMismatch {
+				failed++
+			}
+		}
+
+		if formatSet() || selectionSet() {
+			items := make([]any, len(checks))
+			for i, c := range checks {
+				items[i] = c
+			}
+			printResults(items)
+		} else {
+			for _, c := range checks {
+				if c.Detail != "" {
+					fmt.Printf("%-8s %s (%s)\n", c.Status, c.Name, c.Detail)
+				} else {
+					fmt.Printf("%-8s %s\n", c.Status, c.Name)
+				}
+			}
+		}
+
+		fmt.Fprintf(os.Stderr, "Verified %d files (%d failed)\n", len(checks), failed)
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// verifyResource checks the digest of a downloaded resource against the requested
+// hash, and against the hashes listed for it in the report (when report isn't nil)
+func verifyResource(report *api.Report, hash string, d integrity.Digest) error {
+	if err := d.Match(hash); err != nil {
+		return err
+	}
+
+	if report != nil {
+		if expected, ok := integrity.FromReport(report, hash); ok {
+			return d.Verify(expected)
+		}
+	}
+	return nil
+}
+
+// validResourceHash reports if hash is a hex encoded md5, sha1 or sha256 hash
+func validResourceHash(hash string) bool {
+	if _, err := hex.DecodeString(hash); err != nil {
+		return false
+	}
+	return len(hash) == 32 || len(hash) == 40 || len(hash) == 64
+}
@@ -1,14 +1,12 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"github.com/urlquery/urlquery-api-go"
 )
 
 var reputationCmd = &cobra.Command{
@@ -28,14 +26,11 @@ Example:
 	Run: func(cmd *cobra.Command, args []string) {
 		reputation_url := args[0]
 
-		// API key
-		apikey := viper.GetString("apikey")
-
 		// Initialize API client
-		client := urlquery.NewClient(apikey)
+		client := clientFor(cmd)
 
 		// Fetch reputation data
-		response, err := client.ReputationCheck(reputation_url)
+		response, err := client.CheckReputation(cmd.Context(), reputation_url)
 		if err != nil {
 			fmt.Printf("Error querying URL reputation: %v\n", err)
 			os.Exit(1)
@@ -66,12 +61,7 @@ Example:
 		}
 
 		// Default JSON output
-		out, err := json.MarshalIndent(response, "", "  ")
-		if err != nil {
-			fmt.Println("Error formatting response:", err)
-			os.Exit(1)
-		}
-		fmt.Println(string(out))
+		printResult(response)
 
 	},
 }
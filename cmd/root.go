@@ -3,9 +3,13 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/config"
+	"github.com/urlquery/urlquery-cli/internal/logger"
 )
 
 var cfgFile string
@@ -33,6 +37,9 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "Path to config file (default is $HOME/.urlquery-cli.yaml)")
 
 	// Global flags
+	rootCmd.PersistentFlags().String("profile", "", "Configuration profile to use (can also be set via URLQUERY_PROFILE env var or 'config profile use')")
+	viper.BindPFlag("profile", rootCmd.PersistentFlags().Lookup("profile"))
+
 	rootCmd.PersistentFlags().String("apikey", "", "API Key (can also be set via config file or URLQUERY_APIKEY env var)")
 	viper.BindPFlag("apikey", rootCmd.PersistentFlags().Lookup("apikey"))
 
@@ -42,6 +49,53 @@ func init() {
 	rootCmd.PersistentFlags().Bool("summary", false, "Show a summary output instead of full json")
 	viper.BindPFlag("summary", rootCmd.PersistentFlags().Lookup("summary"))
 
+	rootCmd.PersistentFlags().String("format", "", "Output format: json, ndjson, yaml, csv, table, misp or template=<file> (default json, ndjson for streamed results)")
+	viper.BindPFlag("format", rootCmd.PersistentFlags().Lookup("format"))
+
+	rootCmd.PersistentFlags().StringSliceVar(&fieldsOutput, "fields", nil, "Comma-separated dotted JSON paths to output (e.g. report_id,url.fqdn,stats.alert_count.urlquery)")
+	rootCmd.PersistentFlags().StringVar(&filterOutput, "filter", "", "Only output results matching the expression (e.g. 'stats.alert_count.urlquery>0 && tags contains \"phishing\"')")
+
+	rootCmd.PersistentFlags().Int("retries", 3, "Number of times to retry throttled or failed API requests")
+	viper.BindPFlag("retries", rootCmd.PersistentFlags().Lookup("retries"))
+
+	rootCmd.PersistentFlags().Float64("rate", 0, "Maximum number of API requests per second (0 for no limit)")
+	viper.BindPFlag("rate", rootCmd.PersistentFlags().Lookup("rate"))
+
+	rootCmd.PersistentFlags().Int("rate-burst", 1, "Number of API requests allowed in a burst when --rate is set")
+	viper.BindPFlag("rate_burst", rootCmd.PersistentFlags().Lookup("rate-burst"))
+
+	rootCmd.PersistentFlags().Bool("no-cache", false, "Don't read or store reports in the local report cache")
+	viper.BindPFlag("no_cache", rootCmd.PersistentFlags().Lookup("no-cache"))
+
+	rootCmd.PersistentFlags().Bool("refresh", false, "Fetch reports from the API even when they are cached (e.g. after a rescan), and update the cache")
+	viper.BindPFlag("refresh", rootCmd.PersistentFlags().Lookup("refresh"))
+
+	rootCmd.PersistentFlags().Bool("offline", false, "Don't call the API, only use cached reports")
+	viper.BindPFlag("offline", rootCmd.PersistentFlags().Lookup("offline"))
+
+	rootCmd.PersistentFlags().String("apigw-base", "", "API gateway base URL, e.g. of an on-prem gateway (default https://api.urlquery.net)")
+	viper.BindPFlag("apigw_base", rootCmd.PersistentFlags().Lookup("apigw-base"))
+
+	rootCmd.PersistentFlags().String("proxy", "", "Proxy URL for API requests (default from HTTPS_PROXY, HTTP_PROXY and NO_PROXY)")
+	viper.BindPFlag("proxy", rootCmd.PersistentFlags().Lookup("proxy"))
+
+	rootCmd.PersistentFlags().String("ca-cert", "", "PEM file of CA certificates to trust, in addition to the system ones")
+	viper.BindPFlag("ca_cert", rootCmd.PersistentFlags().Lookup("ca-cert"))
+
+	rootCmd.PersistentFlags().String("client-cert", "", "PEM file of a client certificate for mutual TLS")
+	viper.BindPFlag("client_cert", rootCmd.PersistentFlags().Lookup("client-cert"))
+
+	rootCmd.PersistentFlags().String("client-key", "", "PEM file of the client certificate key (default: the client certificate file)")
+	viper.BindPFlag("client_key", rootCmd.PersistentFlags().Lookup("client-key"))
+
+	rootCmd.PersistentFlags().Duration("http-timeout", api.DefaultHTTPTimeout, "Maximum time of an API request (0 for no limit)")
+	viper.BindPFlag("http_timeout", rootCmd.PersistentFlags().Lookup("http-timeout"))
+
+	rootCmd.PersistentFlags().StringArrayVar(&headersFlag, "header", nil, "Extra header for API requests, e.g. 'X-Tenant: soc' (repeatable)")
+
+	rootCmd.PersistentFlags().Bool("debug", false, "Enable debug logging (API requests, retries) to stderr")
+	viper.BindPFlag("debug", rootCmd.PersistentFlags().Lookup("debug"))
+
 	// env settings
 	viper.SetEnvPrefix("urlquery")
 	viper.AutomaticEnv()
@@ -53,13 +107,40 @@ func init() {
 	viper.BindPFlag("useragent", submitCmd.Flags().Lookup("useragent"))
 	viper.BindPFlag("access", submitCmd.Flags().Lookup("access"))
 	viper.BindPFlag("tags", submitCmd.Flags().Lookup("tags"))
+	submitCmd.Flags().StringVarP(&inputSubmit, "input", "i", "", "File with URLs to submit, one per line (use - for stdin)")
+	submitCmd.Flags().IntVar(&concurrencySubmit, "concurrency", 4, "Number of concurrent submissions when using --input")
+	submitCmd.Flags().BoolVar(&waitSubmit, "wait", false, "Wait for the analysis to finish and output the report")
+	submitCmd.Flags().DurationVar(&timeoutSubmit, "timeout", 10*time.Minute, "Maximum time to wait for the analysis when using --wait")
+	submitCmd.Flags().BoolVar(&notifySubmit, "notify", false, "With --wait, notify the reports with detections to the configured sinks")
 	submitCmd.AddCommand(submitStatusCmd)
 
 	// Search command flags
 	searchCmd.Flags().IntVar(&limitSearch, "limit", 10, "Maximum number of results to return")
 	searchCmd.Flags().IntVar(&offsetSearch, "offset", 0, "Offset for paginated search results")
+	searchCmd.Flags().BoolVar(&allSearch, "all", false, "Fetch all results, streamed as NDJSON")
+	searchCmd.Flags().IntVar(&maxSearch, "max", 0, "Fetch up to N results, streamed as NDJSON")
 
 	reportCmd.Flags().BoolVar(&outputSummary, "summary", false, "Show summary output instead of full report")
+	reportCmd.Flags().StringVar(&packReport, "pack", "", "Pack the bundle of 'all' into an archive: zip or tar.gz")
+	reportCmd.Flags().IntVar(&concurrencyReport, "concurrency", 4, "Number of concurrent downloads for 'all'")
+	reportCmd.Flags().String("quarantine", "", "Quarantine downloaded resources: zip (encrypted, password 'infected') or defang")
+	viper.BindPFlag("quarantine", reportCmd.Flags().Lookup("quarantine"))
+	reportCmd.AddCommand(reportVerifyCmd)
+	reportCmd.AddCommand(reportDiffCmd)
+
+	cachePruneCmd.Flags().DurationVar(&olderThanCache, "older-than", 0, "Also remove reports cached longer ago than this (e.g. 720h)")
+
+	localCmd.PersistentFlags().StringSliceVar(&dirsLocal, "dir", nil, "Also index the reports in these directories (comma-separated)")
+	localSearchCmd.Flags().IntVar(&limitLocal, "limit", 0, "Maximum number of results to return (0 for all)")
+	localSearchCmd.Flags().IntVar(&offsetLocal, "offset", 0, "Offset of the first result to return")
+	localIndexCmd.Flags().BoolVar(&rebuildLocal, "rebuild", false, "Index every report file again")
+
+	monitorCmd.PersistentFlags().StringVar(&stateMonitor, "state", "", "Monitor state file (default: <watchlist>.state.json)")
+	monitorRunCmd.Flags().BoolVar(&onceMonitor, "once", false, "Scan the due URLs once and exit (e.g. from cron)")
+	monitorRunCmd.Flags().DurationVar(&tickMonitor, "tick", time.Minute, "How often to check for due URLs")
+	monitorRunCmd.Flags().DurationVar(&timeoutMonitor, "timeout", 10*time.Minute, "Maximum time to wait for a report, before waiting again next round")
+	monitorRunCmd.Flags().IntVar(&concurrencyMonitor, "concurrency", 4, "Number of URLs scanned at once")
+	monitorRunCmd.Flags().BoolVar(&notifyMonitor, "notify", false, "Notify the reports with detections to the configured sinks")
 
 	// Register commands
 	rootCmd.AddCommand(configCmd)
@@ -68,11 +149,31 @@ func init() {
 	rootCmd.AddCommand(reputationCmd)
 	rootCmd.AddCommand(searchCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(localCmd)
+	rootCmd.AddCommand(monitorCmd)
+	rootCmd.AddCommand(doctorCmd)
 
 	// Add subcommands
+	configShowCmd.Flags().BoolVar(&revealConfig, "reveal", false, "Show secrets such as the API key instead of masking them")
+	configSetCmd.Flags().BoolVar(&encryptConfig, "encrypt", false, "Store the value in the encrypted secrets file (apikey only), prompted when not given")
+
 	configCmd.AddCommand(configShowCmd)
 	configCmd.AddCommand(configSetCmd)
 	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configProfileCmd)
+	configProfileCmd.AddCommand(configProfileListCmd)
+	configProfileCmd.AddCommand(configProfileUseCmd)
+	configProfileCmd.AddCommand(configProfileAddCmd)
+	configProfileCmd.AddCommand(configProfileRemoveCmd)
+	cacheCmd.AddCommand(cacheLsCmd)
+	cacheCmd.AddCommand(cachePruneCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	localCmd.AddCommand(localSearchCmd)
+	localCmd.AddCommand(localIndexCmd)
+	monitorCmd.AddCommand(monitorRunCmd)
+	monitorCmd.AddCommand(monitorStatusCmd)
 }
 
 var rootCmd = &cobra.Command{
@@ -81,9 +182,22 @@ var rootCmd = &cobra.Command{
 	Long:  `A command-line interface for querying and analyzing URLs via the urlquery API.`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		initConfig()
+		warnConfigPermissions()
+
+		logger.EnableDebug()
+		if viper.GetBool("debug") {
+			logger.SetLevel(logger.LevelDebug)
+		}
 
-		// Skip API key check for config-related commands
-		if cmd.Name() == "config" || cmd.HasParent() && cmd.Parent().Name() == "config" {
+		// Skip API key check for config-related and offline commands, doctor checks it
+		if isConfig(cmd) || cmd == doctorCmd {
+			return
+		}
+		if profileErr != nil {
+			fmt.Println("Error:", profileErr)
+			os.Exit(1)
+		}
+		if isOffline(cmd) || viper.GetBool("offline") {
 			return
 		}
 
@@ -100,12 +214,15 @@ var rootCmd = &cobra.Command{
 			viper.Set("summary", cmd.Flag("summary").Value.String())
 		}
 
-		if cmd.Flags().Changed("apigw_base") {
-			viper.Set("apigw_base", cmd.Flag("apigw_base").Value.String())
+		// Read the API key from apikey_command or the secrets file
+		apiKey, err := resolveAPIKey(cmd)
+		if err != nil {
+			fmt.Println("Error reading API key:", err)
+			os.Exit(1)
 		}
+		viper.Set("apikey", apiKey)
 
 		// Check API key value
-		apiKey := viper.GetString("apikey")
 		if apiKey == "" {
 			fmt.Println("Error: API Key is required. Set it via 'config set apikey <value>' or use the --apikey flag.")
 			os.Exit(1)
@@ -113,6 +230,28 @@ var rootCmd = &cobra.Command{
 	},
 }
 
+// Commands annotated as offline (or with an offline parent) work on local files
+// only, and don't need an API key
+const annotationOffline = "offline"
+
+func isOffline(cmd *cobra.Command) bool {
+	for ; cmd != nil; cmd = cmd.Parent() {
+		if cmd.Annotations[annotationOffline] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+func isConfig(cmd *cobra.Command) bool {
+	for ; cmd != nil; cmd = cmd.Parent() {
+		if cmd == configCmd {
+			return true
+		}
+	}
+	return false
+}
+
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
@@ -141,4 +280,44 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err == nil {
 		// fmt.Println("Using config file:", viper.ConfigFileUsed())
 	}
+
+	applyProfile()
+}
+
+// profileErr is set when the selected profile doesn't exist
+var profileErr error
+
+// applyProfile merges the settings of the selected profile over the settings of the
+// config file, so that flags and environment variables still override them
+func applyProfile() {
+	profileErr = nil
+	name := activeProfile()
+	if name == "" {
+		return
+	}
+	if !viper.IsSet(config.KeyProfiles + "." + name) {
+		profileErr = fmt.Errorf("profile '%s' not found, see 'config profile list'", name)
+		return
+	}
+	values := viper.GetStringMap(config.KeyProfiles + "." + name)
+
+	// A profile API key replaces the API key command of the file, and the other way around
+	if _, ok := values["apikey"]; ok {
+		if _, ok := values["apikey_command"]; !ok {
+			values["apikey_command"] = ""
+		}
+	} else if _, ok := values["apikey_command"]; ok {
+		values["apikey"] = ""
+	}
+	viper.MergeConfigMap(values)
+}
+
+// activeProfile returns the name of the selected profile, from the --profile flag,
+// URLQUERY_PROFILE or the config file, or nothing when no profile is used
+func activeProfile() string {
+	name := viper.GetString("profile")
+	if name == config.DefaultProfile {
+		return ""
+	}
+	return name
 }
@@ -1,13 +1,12 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/fatih/color"
-	"github.com/urlquery/urlquery-api-go"
+	"github.com/urlquery/urlquery-cli/internal/api"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -15,28 +14,50 @@ import (
 
 var limitSearch int
 var offsetSearch int
+var allSearch bool
+var maxSearch int
+
+// Page size used when fetching all results, unless --limit is set
+const searchPageSize = 100
 
 var searchCmd = &cobra.Command{
 	Use:   "search <query>",
 	Short: "Search for reports in urlquery.net.",
 	Long: `Searches urlquery.net for reports related to a domain, IP, keyword or text.
-For more details check out: https://urlquery.net/help/search`,
+For more details check out: https://urlquery.net/help/search
+
+By default a single page of results is returned (see --limit and --offset).
+Use --all to fetch every hit, or --max N to fetch up to N hits. The results are
+streamed as one JSON report per line (NDJSON) as the pages are fetched.
+
+Use --filter and --fields to select reports and fields from the results.
+
+Examples:
+  urlquery-cli search example.com
+  urlquery-cli search example.com --all --filter 'stats.alert_count.urlquery>0 && tags contains "phishing"'
+  urlquery-cli search example.com --fields report_id,url.fqdn,stats.alert_count.urlquery --format csv
+  urlquery-cli search example.com --all
+  urlquery-cli search example.com --max 500 --summary`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		search_query := args[0]
 
-		// API key
-		apikey := viper.GetString("apikey")
-
 		// Initialize API client
-		client := urlquery.NewClient(apikey)
+		client := clientFor(cmd)
 
-		// Perform search
-		query := urlquery.NewSearchParams(search_query)
-		query.Limit(limitSearch)
-		query.Offset(offsetSearch)
+		// Fetch all pages
+		if allSearch || maxSearch > 0 {
+			pageSize := searchPageSize
+			if cmd.Flags().Changed("limit") {
+				pageSize = limitSearch
+			}
+			it := api.NewSearchIterator(cmd.Context(), client, search_query, pageSize).Offset(offsetSearch).Max(maxSearch)
+			streamSearch(it, viper.GetBool("summary"))
+			return
+		}
 
-		results, err := client.Search(query)
+		// Perform search
+		results, err := client.Search(cmd.Context(), search_query, limitSearch, offsetSearch)
 		if err != nil {
 			fmt.Printf("Error searching reports: %v\n", err)
 			os.Exit(1)
@@ -51,29 +72,57 @@ For more details check out: https://urlquery.net/help/search`,
 			fmt.Printf("Offset:  %d\n\n", results.Offset)
 
 			for _, v := range results.Reports {
-				url := v.Url.Addr
-				if len(url) > 76 {
-					url = url[:70] + " (...)"
-				}
-
-				fmt.Println("\n--------------------------------------------------------------------------------")
-				// fmt.Printf("📝 Report ID:  %s\n", v.ID)
-				color.New(color.Bold).Printf("📝 Report ID:  %s\n", v.ID)
-				fmt.Printf("🔗 URL:        %s\n", url)
-				fmt.Printf("🚨 Detections: %d\n", v.Stats.AlertCount.Urlquery)
-				fmt.Printf("🏷️  Tags:       %s\n", strings.Join(v.Tags, " "))
+				printReportOverview(&v)
 			}
 			fmt.Println("")
 			return
 		}
 
-		// Default full JSON output
-		output, err := json.MarshalIndent(results, "", "  ")
-		if err != nil {
+		// Filtered reports, or the full JSON output
+		if selectionSet() {
+			printResults(results.Items())
+			return
+		}
+		printResult(results)
+
+	},
+}
+
+// streamSearch writes every result of the iterator in the selected format (default NDJSON), or as a summary
+func streamSearch(it *api.SearchIterator, summary bool) {
+	stream := newResultStream()
+
+	count := 0
+	for it.Next() {
+		if summary {
+			printReportOverview(it.Report())
+		} else if err := writeResult(stream, it.Report()); err != nil {
 			fmt.Println("Error formatting response:", err)
 			os.Exit(1)
 		}
-		fmt.Println(string(output))
+		count++
+	}
 
-	},
+	if err := stream.Close(); err != nil {
+		fmt.Println("Error formatting response:", err)
+		os.Exit(1)
+	}
+	if err := it.Err(); err != nil {
+		fmt.Printf("Error searching reports: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Fetched %d of %d hits\n", count, it.TotalHits())
+}
+
+func printReportOverview(v *api.ReportOverview) {
+	url := v.Url.Addr
+	if len(url) > 76 {
+		url = url[:70] + " (...)"
+	}
+
+	fmt.Println("\n--------------------------------------------------------------------------------")
+	color.New(color.Bold).Printf("📝 Report ID:  %s\n", v.ID)
+	fmt.Printf("🔗 URL:        %s\n", url)
+	fmt.Printf("🚨 Detections: %d\n", v.Stats.AlertCount.Urlquery)
+	fmt.Printf("🏷️  Tags:       %s\n", strings.Join(v.Tags, " "))
 }
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/urlquery/urlquery-cli/internal/secrets"
+	"golang.org/x/term"
+)
+
+// secretsFilePath returns the path of the encrypted secrets file
+func secretsFilePath() string {
+	if path := viper.GetString("secrets_file"); path != "" {
+		return path
+	}
+	return filepath.Join(filepath.Dir(configFilePath()), ".urlquery-cli.secrets")
+}
+
+// readPassphrase returns the passphrase of the secrets file, from URLQUERY_PASSPHRASE
+// or prompted on the terminal. A new passphrase is prompted twice.
+func readPassphrase(confirm bool) ([]byte, error) {
+	if passphrase, ok := os.LookupEnv("URLQUERY_PASSPHRASE"); ok {
+		return []byte(passphrase), nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil, errors.New("the secrets file needs a passphrase: set URLQUERY_PASSPHRASE or run from a terminal")
+	}
+
+	passphrase, err := readHidden("Secrets passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(passphrase) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	if confirm {
+		again, err := readHidden("Repeat passphrase: ")
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(passphrase, again) {
+			return nil, errors.New("passphrases don't match")
+		}
+	}
+	return passphrase, nil
+}
+
+// readHidden prompts on stderr and reads a line from the terminal without echo
+func readHidden(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	defer fmt.Fprintln(os.Stderr)
+	return term.ReadPassword(int(os.Stdin.Fd()))
+}
+
+// openSecrets decrypts the secrets file, prompting for its passphrase
+func openSecrets() (*secrets.Store, error) {
+	path := secretsFilePath()
+	passphrase, err := readPassphrase(!secrets.Exists(path))
+	if err != nil {
+		return nil, err
+	}
+	return secrets.Open(path, passphrase)
+}
+
+// resolveAPIKey returns the API key: from apikey_command when set (unless the key is
+// given with --apikey or URLQUERY_APIKEY), or the apikey setting, decrypted from the
+// secrets file when it refers to a secret
+func resolveAPIKey(cmd *cobra.Command) (string, error) {
+	apikey := viper.GetString("apikey")
+	_, fromEnv := os.LookupEnv("URLQUERY_APIKEY")
+	overridden := cmd.Flags().Changed("apikey") || fromEnv
+
+	if command := viper.GetString("apikey_command"); command != "" && !overridden {
+		return runAPIKeyCommand(command)
+	}
+
+	name, ok := secrets.Ref(apikey)
+	if !ok {
+		return apikey, nil
+	}
+	store, err := openSecrets()
+	if err != nil {
+		return "", err
+	}
+	apikey, ok = store.Get(name)
+	if !ok {
+		return "", fmt.Errorf("secret '%s' not found in %s", name, secretsFilePath())
+	}
+	return apikey, nil
+}
+
+// runAPIKeyCommand runs a shell command printing the API key, e.g. 'pass show urlquery'.
+// The terminal is left to the command, for passphrase prompts.
+func runAPIKeyCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var c *exec.Cmd
+	if runtime.GOOS == "windows" {
+		c = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		c = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	var stdout bytes.Buffer
+	c.Stdin = os.Stdin
+	c.Stdout = &stdout
+	c.Stderr = os.Stderr
+	if err := c.Run(); err != nil {
+		return "", fmt.Errorf("apikey_command failed: %w", err)
+	}
+
+	// Like pass, the key is on the first line
+	apikey, _, _ := strings.Cut(stdout.String(), "\n")
+	apikey = strings.TrimSpace(apikey)
+	if apikey == "" {
+		return "", errors.New("apikey_command printed no API key")
+	}
+	return apikey, nil
+}
+
+// secretKeys are the names of settings holding secrets, masked by 'config show'
+var secretKeys = map[string]bool{
+	"apikey":        true,
+	"password":      true,
+	"authorization": true,
+	"token":         true,
+}
+
+// maskSecrets returns a copy of a setting with its secrets masked. Nested settings are
+// masked by their own key, e.g. the password of a notify sink.
+func maskSecrets(key string, value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		masked := make(map[string]any, len(v))
+		for k, item := range v {
+			masked[k] = maskSecrets(k, item)
+		}
+		return masked
+	case []any:
+		masked := make([]any, len(v))
+		for i, item := range v {
+			masked[i] = maskSecrets("", item)
+		}
+		return masked
+	case string:
+		if _, ok := secrets.Ref(v); ok || v == "" {
+			return v
+		}
+		if key := strings.ToLower(key[strings.LastIndex(key, ".")+1:]); secretKeys[key] {
+			return maskSecret(v)
+		}
+	}
+	return value
+}
+
+// maskSecret only keeps the end of long secrets, to tell them apart
+func maskSecret(s string) string {
+	if len(s) < 12 {
+		return "****"
+	}
+	return "****" + s[len(s)-4:]
+}
+
+// warnConfigPermissions warns when the config file may be read by other users
+func warnConfigPermissions() {
+	path := viper.ConfigFileUsed()
+	if path == "" || runtime.GOOS == "windows" {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		fmt.Fprintf(os.Stderr, "Warning: config file %s is readable by other users (%#o), run: chmod 600 %s\n", path, info.Mode().Perm(), path)
+	}
+}
+
+// encryptSetting stores the API key in the secrets file, prompting for it when empty,
+// and returns the reference to store in the config file instead
+func encryptSetting(key, profile, value string) string {
+	if key != "apikey" {
+		fmt.Println("Error: only apikey can be stored in the secrets file")
+		os.Exit(1)
+	}
+
+	if value == "" {
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			fmt.Println("Error: missing API key, it can only be prompted from a terminal")
+			os.Exit(1)
+		}
+		input, err := readHidden("API key: ")
+		if err != nil || len(input) == 0 {
+			fmt.Println("Error: missing API key")
+			os.Exit(1)
+		}
+		value = string(input)
+	}
+
+	name := key
+	if profile != "" {
+		name = profile + "." + key
+	}
+
+	store, err := openSecrets()
+	if err != nil {
+		fmt.Println("Error opening secrets file:", err)
+		os.Exit(1)
+	}
+	store.Set(name, value)
+	if err := store.Save(); err != nil {
+		fmt.Println("Error saving secrets file:", err)
+		os.Exit(1)
+	}
+	return secrets.RefPrefix + name
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// useConfig reads a config file with the content for the test, and an empty config
+// file afterwards
+func useConfig(t *testing.T, content string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	empty := filepath.Join(dir, "empty.yaml")
+	if err := os.WriteFile(empty, []byte("{}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	old := cfgFile
+	cfgFile = path
+	initConfig()
+	t.Cleanup(func() {
+		cfgFile = empty
+		initConfig()
+		cfgFile = old
+	})
+}
+
+const secretsConfig = `apikey: abcdef1234567890
+notify:
+  sinks:
+    - type: webhook
+      url: https://soar.example.com/hooks/urlquery
+      headers: {Authorization: Bearer hooktoken123}
+    - type: smtp
+      addr: smtp.example.com:587
+      from: urlquery@example.com
+      to: [soc@example.com]
+      password: smtp-password
+`
+
+func TestConfigShowMasks(t *testing.T) {
+	useConfig(t, secretsConfig)
+
+	out := run(t, nil, configShowCmd)
+	for _, secret := range []string{"abcdef1234567890", "hooktoken123", "smtp-password"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("%s not masked in:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "apikey: ****7890") || !strings.Contains(out, "password:****") {
+		t.Errorf("secrets not masked in:\n%s", out)
+	}
+	if !strings.Contains(out, "soc@example.com") {
+		t.Errorf("settings missing in:\n%s", out)
+	}
+}
+
+func TestConfigShowReveal(t *testing.T) {
+	useConfig(t, secretsConfig)
+	setFlag(t, configShowCmd, "reveal", "true")
+
+	out := run(t, nil, configShowCmd)
+	for _, secret := range []string{"abcdef1234567890", "hooktoken123", "smtp-password"} {
+		if !strings.Contains(out, secret) {
+			t.Errorf("%s not revealed in:\n%s", secret, out)
+		}
+	}
+}
+
+func TestMaskSecrets(t *testing.T) {
+	tests := []struct {
+		key   string
+		value any
+		want  any
+	}{
+		{"apikey", "abcdef1234567890", "****7890"},
+		{"apikey", "short", "****"},
+		{"apikey", "secret:apikey", "secret:apikey"},
+		{"apikey", "", ""},
+		{"profiles.team.apikey", "abcdef1234567890", "****7890"},
+		{"useragent", "abcdef1234567890", "abcdef1234567890"},
+		{"timeout", 30, 30},
+	}
+	for _, tt := range tests {
+		if got := maskSecrets(tt.key, tt.value); got != tt.want {
+			t.Errorf("maskSecrets(%s, %v) = %v, want %v", tt.key, tt.value, got, tt.want)
+		}
+	}
+
+	notify := map[string]any{"sinks": []any{
+		map[string]any{"type": "smtp", "password": "smtp-password", "headers": map[string]any{"Authorization": "Bearer hooktoken123"}},
+	}}
+	sink := maskSecrets("notify", notify).(map[string]any)["sinks"].([]any)[0].(map[string]any)
+	if sink["password"] != "****word" || sink["headers"].(map[string]any)["Authorization"] != "****n123" || sink["type"] != "smtp" {
+		t.Errorf("nested secrets = %v", sink)
+	}
+	if notify["sinks"].([]any)[0].(map[string]any)["password"] != "smtp-password" {
+		t.Error("maskSecrets changed the setting")
+	}
+}
+
+func TestResolveAPIKey(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("apikey_command runs sh")
+	}
+
+	tests := []struct {
+		name   string
+		config string
+		flag   string
+		env    string
+		want   string
+	}{
+		{"file", "apikey: filekey\n", "", "", "filekey"},
+		{"command", "apikey: filekey\napikey_command: echo commandkey\n", "", "", "commandkey"},
+		{"flag", "apikey_command: echo commandkey\n", "flagkey", "", "flagkey"},
+		{"env", "apikey_command: echo commandkey\n", "", "envkey", "envkey"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("URLQUERY_APIKEY", tt.env)
+			}
+			useConfig(t, tt.config)
+			reportCmd.InheritedFlags() // Adds the flags of rootCmd, like parsing the arguments
+			if tt.flag != "" {
+				setFlag(t, reportCmd, "apikey", tt.flag)
+			}
+
+			got, err := resolveAPIKey(reportCmd)
+			if err != nil || got != tt.want {
+				t.Errorf("resolveAPIKey() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunAPIKeyCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands need sh")
+	}
+
+	tests := []struct {
+		command string
+		want    string
+		wantErr bool
+	}{
+		{`printf 'abc123\nurl: https://urlquery.net\n'`, "abc123", false},
+		{`printf 'abc123'`, "abc123", false},
+		{`echo '  abc123  '`, "abc123", false},
+		{`printf '\r\nabc123\n'`, "", true},
+		{`printf ''`, "", true},
+		{`exit 3`, "", true},
+	}
+	for _, tt := range tests {
+		got, err := runAPIKeyCommand(tt.command)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("runAPIKeyCommand(%s) = %q, %v", tt.command, got, err)
+		}
+	}
+}
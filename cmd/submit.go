@@ -1,21 +1,31 @@
 package cmd
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/fatih/color"
-	"github.com/urlquery/urlquery-api-go"
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/notify"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+var waitSubmit bool
+var timeoutSubmit time.Duration
+var notifySubmit bool
+
+// Notifier of the detections found with --wait --notify
+var submitNotifier *notify.Notifier
+
 var submitCmd = &cobra.Command{
-	Use:   "submit <url>",
+	Use:   "submit <url> | --input <file>",
 	Short: "Submit a URL for sandbox analysis and threat detection.",
 	Long: `Submit a URL to urlquery.net for sandbox analysis and threat detection.
 
@@ -26,66 +36,90 @@ You can customize the submission with:
 
 Requires an API key (set via 'config set apikey <value>' or --apikey).
 
+Multiple URLs can be submitted from a file (one URL per line) or stdin with --input.
+Duplicate URLs are only submitted once, and each result is written as one JSON
+object per line (NDJSON). Failed submissions are included with an error and the
+HTTP status code returned by the API.
+
 Example:
   urlquery-cli submit https://example.com
-`,
-	Args: cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		submit_url := args[0]
+  urlquery-cli submit --input urls.txt --concurrency 8
+  cat urls.txt | urlquery-cli submit --input -
 
-		apikey := viper.GetString("apikey")
-		if apikey == "" {
-			fmt.Println("Error: API Key is required. Set it via 'config set apikey <value>' or use the --apikey flag.")
-			os.Exit(1)
-		}
+Use --wait to poll the queue until the analysis is done, and output the report
+(or a summary with --summary). Status changes are written to stderr. When combined
+with --input, the final queue status is written for every URL instead.
 
-		job := urlquery.SubmitJob{
-			Url: submit_url,
-		}
+Example:
+  urlquery-cli submit https://example.com --wait --timeout 5m --summary
 
-		job.UserAgent = viper.GetString("useragent")
-		if job.UserAgent == "" {
-			job.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:138.0) Gecko/20100101 Firefox/138.0"
-		}
+With --wait, --notify sends a notification to the sinks of the notify section of the
+config file when a report has detections (see 'config --help').
 
-		// Validate tags
-		tags := viper.GetString("tags")
-		if tags != "" {
-			tmpTags := strings.Split(tags, ",")
-			var validTags []string
-			for _, tag := range tmpTags {
-				if !regexp.MustCompile(`^[a-zA-Z0-9_]+$`).MatchString(strings.Trim(tag, " ")) {
-					fmt.Printf("Removed invalid tag: %s (tags must be alphanumeric or underscore)\n", tag)
-					continue
-				}
-				validTags = append(validTags, strings.Trim(tag, " "))
+Example:
+  urlquery-cli submit --input urls.txt --wait --notify
+`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flags().Changed("input") {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := cmd.Context()
+		client := clientFor(cmd)
+		job := newSubmitJob()
+
+		if notifySubmit {
+			if !waitSubmit {
+				fmt.Println("Error: --notify requires --wait")
+				os.Exit(1)
 			}
-			job.Tags = validTags
+			submitNotifier = newNotifier()
 		}
 
-		// Validate access value
-		access := viper.GetString("access")
-		validAccess := map[string]bool{
-			"public":     true,
-			"restricted": true,
-			"private":    true,
-		}
-		if !validAccess[access] {
-			access = "public"
+		// Batch submission from file or stdin
+		if cmd.Flags().Changed("input") {
+			runBatchSubmit(ctx, client, job, inputSubmit, concurrencySubmit)
+			return
 		}
-		job.Access = access
 
-		// Submit URL
-		client := urlquery.NewClient(apikey)
-		client.Submit(job)
+		job.Url = args[0]
 
-		response, err := client.Submit(job)
+		// Submit URL
+		response, err := client.Submit(ctx, job)
 		if err != nil {
 			fmt.Printf("Error querying URL: %v\n", err)
 			return
 		}
 
 		summary := viper.GetBool("summary")
+
+		// Wait for the analysis to finish and output the report
+		if waitSubmit {
+			done, err := waitForReport(ctx, client, response)
+			if err != nil {
+				fmt.Printf("Error waiting for Queue ID %s: %v\n", response.QueueID, err)
+				os.Exit(1)
+			}
+
+			report, err := client.GetReport(ctx, done.ReportID)
+			if err != nil {
+				fmt.Printf("Error fetching report %s: %v\n", done.ReportID, err)
+				os.Exit(1)
+			}
+			if submitNotifier != nil {
+				notifyReport(ctx, client, submitNotifier, job.Url, report)
+			}
+
+			if summary {
+				fmt.Println(SummarizeReport(report))
+				return
+			}
+			printResult(report)
+			return
+		}
+
 		if summary {
 
 			bold := color.New(color.Bold).SprintFunc()
@@ -99,16 +133,70 @@ Example:
 		}
 
 		// Default JSON output
-		output, err := json.MarshalIndent(response, "", "  ")
-		if err != nil {
-			fmt.Println("Error formatting response:", err)
-			os.Exit(1)
-		}
-		fmt.Println(string(output))
+		printResult(response)
 
 	},
 }
 
+// waitForReport blocks until a queued submission is done (or --timeout is reached),
+// printing every status transition to stderr.
+func waitForReport(ctx context.Context, client api.Service, job *api.QueuedJob) (*api.QueuedJob, error) {
+	if timeoutSubmit > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeoutSubmit)
+		defer cancel()
+	}
+
+	start := time.Now()
+	done, err := client.WaitForReport(ctx, job.QueueID, func(j *api.QueuedJob) {
+		fmt.Fprintf(os.Stderr, "[%6s] %s %s: %s\n", time.Since(start).Round(time.Second), j.QueueID, j.Url.Addr, j.Status)
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("timed out after %s", timeoutSubmit)
+	}
+	return done, err
+}
+
+// newSubmitJob builds a submission from the configured useragent, tags and access level.
+// The URL is left empty and must be set by the caller.
+func newSubmitJob() api.SubmitJob {
+	var job api.SubmitJob
+
+	job.UserAgent = viper.GetString("useragent")
+	if job.UserAgent == "" {
+		job.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:138.0) Gecko/20100101 Firefox/138.0"
+	}
+
+	// Validate tags
+	tags := viper.GetString("tags")
+	if tags != "" {
+		tmpTags := strings.Split(tags, ",")
+		var validTags []string
+		for _, tag := range tmpTags {
+			if !regexp.MustCompile(`^[a-zA-Z0-9_]+$`).MatchString(strings.Trim(tag, " ")) {
+				fmt.Fprintf(os.Stderr, "Removed invalid tag: %s (tags must be alphanumeric or underscore)\n", tag)
+				continue
+			}
+			validTags = append(validTags, strings.Trim(tag, " "))
+		}
+		job.Tags = validTags
+	}
+
+	// Validate access value
+	access := viper.GetString("access")
+	validAccess := map[string]bool{
+		"public":     true,
+		"restricted": true,
+		"private":    true,
+	}
+	if !validAccess[access] {
+		access = "public"
+	}
+	job.Access = access
+
+	return job
+}
+
 // Sub command of submit which returns the current status of a submission
 var submitStatusCmd = &cobra.Command{
 	Use:   "status <queue_id>",
@@ -132,26 +220,14 @@ Example:
 	Run: func(cmd *cobra.Command, args []string) {
 		queue_id := args[0]
 
-		apikey := viper.GetString("apikey")
-		if apikey == "" {
-			fmt.Println("Error: API Key is required. Set it via 'config set apikey <value>' or use the --apikey flag.")
-			os.Exit(1)
-		}
+		client := clientFor(cmd)
 
-		client := urlquery.NewClient(apikey)
-
-		response, err := client.GetQueueStatus(queue_id)
+		response, err := client.QueueStatus(cmd.Context(), queue_id)
 		if err != nil {
 			fmt.Printf("Error fetching status for Queue ID %s: %v\n", queue_id, err)
 			os.Exit(1)
 		}
 
-		output, err := json.MarshalIndent(response, "", "  ")
-		if err != nil {
-			fmt.Println("Error formatting response:", err)
-			os.Exit(1)
-		}
-
-		fmt.Println(string(output))
+		printResult(response)
 	},
 }
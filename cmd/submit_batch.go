@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/output"
+)
+
+var inputSubmit string
+var concurrencySubmit int
+
+// Status of the batch results whose submission failed. It is set by the CLI, the
+// API has no such queue status.
+const batchStatusFailed = "failed"
+
+// batchResult is written for every URL in a batch submission. Failed submissions
+// keep the submitted URL and carry the error and the HTTP status code from the API.
+type batchResult struct {
+	api.QueuedJob
+
+	Error       string `json:"error,omitempty"`
+	ErrorStatus int    `json:"error_status,omitempty"`
+}
+
+func runBatchSubmit(ctx context.Context, client api.Service, job api.SubmitJob, input string, workers int) {
+	var r io.Reader = os.Stdin
+	if input != "-" {
+		f, err := os.Open(input)
+		if err != nil {
+			fmt.Println("Error opening input file:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	urls, err := readURLList(r)
+	if err != nil {
+		fmt.Println("Error reading input:", err)
+		os.Exit(1)
+	}
+
+	stream := newResultStream()
+	failed := submitBatch(ctx, client, job, urls, workers, stream)
+	if err := stream.Close(); err != nil {
+		fmt.Println("Error formatting response:", err)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "Submitted %d of %d URLs (%d failed)\n", len(urls)-failed, len(urls), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// readURLList reads one URL per line, skipping blank lines, comments (#) and duplicates.
+func readURLList(r io.Reader) ([]string, error) {
+	var urls []string
+	seen := make(map[string]bool)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		urls = append(urls, line)
+	}
+
+	return urls, scanner.Err()
+}
+
+// submitBatch submits every URL using a fixed number of workers, and writes the
+// results to the stream in the order they complete. Returns the number of failures.
+func submitBatch(ctx context.Context, client api.Service, job api.SubmitJob, urls []string, workers int, w *output.Stream) int {
+	if workers < 1 {
+		workers = 1
+	}
+
+	jobs := make(chan string)
+	results := make(chan batchResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range jobs {
+				results <- submitOne(ctx, client, job, u)
+			}
+		}()
+	}
+
+	go func() {
+		for _, u := range urls {
+			jobs <- u
+		}
+		close(jobs)
+		wg.Wait()
+		close(results)
+	}()
+
+	failed := 0
+	for res := range results {
+		if res.Error != "" {
+			failed++
+		}
+		if err := writeResult(w, res); err != nil {
+			fmt.Fprintln(os.Stderr, "Error formatting result:", err)
+		}
+	}
+
+	return failed
+}
+
+func submitOne(ctx context.Context, client api.Service, job api.SubmitJob, u string) batchResult {
+	job.Url = u
+
+	queued, err := client.Submit(ctx, job)
+	if err != nil {
+		var res batchResult
+		res.Url.Addr = u
+		res.Status = batchStatusFailed
+		res.setError(err)
+		return res
+	}
+
+	if waitSubmit {
+		done, err := waitForReport(ctx, client, queued)
+		if err != nil {
+			res := batchResult{QueuedJob: *queued}
+			res.setError(err)
+			return res
+		}
+		queued = done
+
+		if submitNotifier != nil {
+			report, err := client.GetReport(ctx, done.ReportID)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error fetching report %s: %v\n", done.ReportID, err)
+			} else {
+				notifyReport(ctx, client, submitNotifier, u, report)
+			}
+		}
+	}
+
+	return batchResult{QueuedJob: *queued}
+}
+
+func (r batchResult) Columns() []string {
+	return append(r.QueuedJob.Columns(), "error", "error_status")
+}
+
+func (r batchResult) Rows() [][]string {
+	row := r.QueuedJob.Rows()[0]
+	status := ""
+	if r.ErrorStatus != 0 {
+		status = strconv.Itoa(r.ErrorStatus)
+	}
+	return [][]string{append(row, r.Error, status)}
+}
+
+func (r *batchResult) setError(err error) {
+	r.Error = err.Error()
+
+	var apiErr *api.UrlqueryApiError
+	if errors.As(err, &apiErr) {
+		r.ErrorStatus = apiErr.StatusCode
+	}
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/output"
+)
+
+func TestReadURLList(t *testing.T) {
+	input := `
+# phishing campaign
+https://example.com/login
+  https://example.org/  
+
+https://example.com/login
+#https://example.net/
+`
+	urls, err := readURLList(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://example.com/login", "https://example.org/"}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("urls = %q, want %q", urls, want)
+	}
+}
+
+// batch submits the URLs and returns the number of failures and the results
+func batch(t *testing.T, fake *fakeService, urls []string, workers int) (int, map[string]batchResult) {
+	t.Helper()
+	f, err := output.New("ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	stream := output.NewStream(&buf, f)
+	failed := submitBatch(context.Background(), fake, api.SubmitJob{Access: "private"}, urls, workers, stream)
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	results := map[string]batchResult{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var res batchResult
+		if err := json.Unmarshal([]byte(line), &res); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		results[res.Url.Addr] = res
+	}
+	return failed, results
+}
+
+func TestSubmitBatch(t *testing.T) {
+	var urls []string
+	for i := 0; i < 12; i++ {
+		urls = append(urls, fmt.Sprintf("https://example.com/%d", i))
+	}
+	fake := &fakeService{
+		delay: 10 * time.Millisecond,
+		fail:  map[string]error{urls[3]: &api.UrlqueryApiError{StatusCode: 429, Message: "too many requests"}},
+	}
+
+	failed, results := batch(t, fake, urls, 3)
+	if failed != 1 || len(results) != len(urls) {
+		t.Fatalf("failed = %d, results = %d", failed, len(results))
+	}
+	if fake.maxInflight < 2 || fake.maxInflight > 3 {
+		t.Errorf("%d submissions at once with 3 workers", fake.maxInflight)
+	}
+	for _, job := range fake.submitted {
+		if job.Access != "private" {
+			t.Errorf("job settings not used: %+v", job)
+		}
+	}
+
+	res := results[urls[3]]
+	if res.Status != "failed" || res.ErrorStatus != 429 || !strings.Contains(res.Error, "too many requests") {
+		t.Errorf("failed submission = %+v", res)
+	}
+	if res := results[urls[0]]; res.Error != "" || res.QueueID == "" {
+		t.Errorf("submission = %+v", res)
+	}
+}
+
+func TestSubmitBatchWorkers(t *testing.T) {
+	fake := &fakeService{delay: time.Millisecond}
+	if failed, _ := batch(t, fake, []string{"https://example.com/", "https://example.org/"}, 0); failed != 0 {
+		t.Errorf("failed = %d", failed)
+	}
+	if fake.maxInflight != 1 {
+		t.Errorf("%d submissions at once with 0 workers, want 1", fake.maxInflight)
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/dustin/go-humanize"
 	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/diff"
 )
 
 // Template for report summary
@@ -78,6 +79,91 @@ No domain summary available.
 {{ end }}
 `
 
+// Template for report diff summary
+const diffSummaryTemplate = `
+🔀 Report diff
+📝 From : {{.From.ReportID}} (v{{.From.Version}}, {{formatDate .From.Date}}) {{.From.URL}}
+📝 To   : {{.To.ReportID}} (v{{.To.Version}}, {{formatDate .To.Date}}) {{.To.URL}}
+{{- if not .Changed}}
+
+No changes.
+{{- end}}
+{{- with .FinalURL}}
+
+🔗 Final URL:
+   - {{.From}}
+   + {{.To}}
+{{- end}}
+{{- with .Title}}
+
+📄 Webpage Title:
+   - {{.From}}
+   + {{.To}}
+{{- end}}
+{{- if not .Domains.Empty}}
+
+🌍 Domains:
+{{- range .Domains.Added}}
+   + {{.}}
+{{- end}}
+{{- range .Domains.Removed}}
+   - {{.}}
+{{- end}}
+{{- end}}
+{{- if not .IPs.Empty}}
+
+🌐 IPs:
+{{- range .IPs.Added}}
+   + {{.}}
+{{- end}}
+{{- range .IPs.Removed}}
+   - {{.}}
+{{- end}}
+{{- end}}
+{{- if not .ASNs.Empty}}
+
+🌐 ASNs:
+{{- range .ASNs.Added}}
+   + {{.}}
+{{- end}}
+{{- range .ASNs.Removed}}
+   - {{.}}
+{{- end}}
+{{- end}}
+{{- if not .Resources.Empty}}
+
+📦 Resources:
+{{- range .Resources.Added}}
+   + {{.Sha256}}  {{humanizeBytes .Size}}  {{.URL}}
+{{- end}}
+{{- range .Resources.Removed}}
+   - {{.Sha256}}  {{humanizeBytes .Size}}  {{.URL}}
+{{- end}}
+{{- end}}
+{{- if .Alerts}}
+
+🚨 Alerts:
+{{- range .Alerts}}
+   {{.Sensor}}
+{{- range .Added}}
+      + {{.}}
+{{- end}}
+{{- range .Removed}}
+      - {{.}}
+{{- end}}
+{{- end}}
+{{- end}}
+{{- if .Certificates}}
+
+🔐 Certificates:
+{{- range .Certificates}}
+   {{.Fqdn}}
+      - {{.From}}
+      + {{.To}}
+{{- end}}
+{{- end}}
+`
+
 // Custom template functions
 var templateFunctions = template.FuncMap{
 	"join": strings.Join,
@@ -120,3 +206,17 @@ func SummarizeReport(report *api.Report) string {
 
 	return buf.String()
 }
+
+// SummarizeDiff generates a formatted summary of the changes between two reports
+func SummarizeDiff(d *diff.Diff) string {
+	tmpl, err := template.New("diff").Funcs(templateFunctions).Parse(diffSummaryTemplate)
+	if err != nil {
+		return fmt.Sprintf("Error parsing template: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, d); err != nil {
+		return fmt.Sprintf("Error executing template: %v", err)
+	}
+	return buf.String()
+}
@@ -0,0 +1,22 @@
+// Package apitest provides a report for the tests of the packages which read reports
+package apitest
+
+import (
+	_ "embed"
+	"encoding/json"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+//go:embed testdata/report.json
+var reportJSON []byte
+
+// Report returns a new copy of the test report: a phishing page on login.example.com,
+// which loads a script with detections from cdn.example.net
+func Report() *api.Report {
+	var r api.Report
+	if err := json.Unmarshal(reportJSON, &r); err != nil {
+		panic(err)
+	}
+	return &r
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/urlquery/urlquery-cli/internal/logger"
+)
+
+// ErrOffline is returned by clients in offline mode for anything not available locally
+var ErrOffline = errors.New("offline mode: not available without the API")
+
+// ReportStore keeps reports between runs. A stored report is returned by GetReport
+// without calling the API, even if the report has since been analyzed again (use
+// Refresh to fetch it).
+type ReportStore interface {
+	Get(report_id string) (*Report, bool)
+	Put(r *Report) error
+}
+
+// Read reports from a store before calling the API, and store fetched reports
+func ReportCache(store ReportStore) OptionsClientFunc {
+	return func(client *httpClient) error {
+		client.reports = store
+		return nil
+	}
+}
+
+// Fetch reports from the API instead of reading them from the store, and store them
+func Refresh() OptionsClientFunc {
+	return func(client *httpClient) error {
+		client.refresh = true
+		return nil
+	}
+}
+
+// Offline mode: requests are never sent, only stored reports are available
+func Offline() OptionsClientFunc {
+	return func(client *httpClient) error {
+		client.offline = true
+		return nil
+	}
+}
+
+// cachedReport returns a stored report, if any
+func (c *httpClient) cachedReport(report_id string) (*Report, bool) {
+	if c.reports == nil || c.refresh {
+		return nil, false
+	}
+
+	r, ok := c.reports.Get(report_id)
+	if ok {
+		logger.Debug("Report %s (version %d) read from cache", report_id, r.Version)
+	}
+	return r, ok
+}
+
+// storeReport stores a completed report. Reports still being analyzed are not stored.
+func (c *httpClient) storeReport(r *Report) {
+	if c.reports == nil || r.ID == "" {
+		return
+	}
+
+	switch r.Status {
+	case StatusQueued, StatusProcessing, StatusAnalyzing:
+		return
+	}
+
+	if err := c.reports.Put(r); err != nil {
+		logger.Debug("Failed to cache report %s: %v", r.ID, err)
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type memoryStore map[string]*Report
+
+func (m memoryStore) Get(id string) (*Report, bool) {
+	r, ok := m[id]
+	return r, ok
+}
+
+func (m memoryStore) Put(r *Report) error {
+	m[r.ID] = r
+	return nil
+}
+
+func TestReportCache(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"report_id": "r1", "version": 1, "status": "done"}`))
+	}))
+	defer server.Close()
+
+	store := memoryStore{}
+	client, err := NewClient(ApiGWBase(server.URL), ReportCache(store))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		r, err := client.GetReport(context.Background(), "r1")
+		if err != nil || r.ID != "r1" {
+			t.Fatalf("GetReport() = %+v, %v", r, err)
+		}
+	}
+	if requests != 1 || store["r1"] == nil {
+		t.Errorf("Expected 1 request and a stored report, got %d requests", requests)
+	}
+
+	// Refreshed, reports are fetched again and stored
+	refresh, _ := NewClient(ApiGWBase(server.URL), ReportCache(store), Refresh())
+	store["r1"] = &Report{}
+	if r, err := refresh.GetReport(context.Background(), "r1"); err != nil || r.ID != "r1" {
+		t.Fatalf("GetReport() = %+v, %v", r, err)
+	}
+	if requests != 2 || store["r1"].ID != "r1" {
+		t.Errorf("Expected 2 requests and a stored report, got %d requests", requests)
+	}
+
+	// Offline, only stored reports are available
+	offline, _ := NewClient(ApiGWBase(server.URL), ReportCache(store), Offline())
+	if _, err := offline.GetReport(context.Background(), "r1"); err != nil {
+		t.Errorf("GetReport() error = %v", err)
+	}
+	if _, err := offline.GetReport(context.Background(), "r2"); !errors.Is(err, ErrOffline) {
+		t.Errorf("Expected ErrOffline, got %v", err)
+	}
+	if _, err := offline.Search(context.Background(), "example.com", 10, 0); !errors.Is(err, ErrOffline) {
+		t.Errorf("Expected ErrOffline, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Offline client sent %d requests", requests-2)
+	}
+}
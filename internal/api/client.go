@@ -7,7 +7,11 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/logger"
 )
 
 const (
@@ -39,6 +43,13 @@ type httpClient struct {
 	headers   map[string]string
 	apiKey    string
 	userAgent string
+
+	retry   RetryPolicy
+	limiter *rateLimiter
+
+	reports ReportStore
+	refresh bool
+	offline bool
 }
 
 func NewClient(opts ...OptionsClientFunc) (*httpClient, error) {
@@ -71,7 +82,11 @@ func ApiKey(key string) OptionsClientFunc {
 // Custom API Gateway
 func ApiGWBase(apigw_base string) OptionsClientFunc {
 	return func(client *httpClient) error {
-		client.baseURL = apigw_base
+		u, err := url.Parse(apigw_base)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("invalid API gateway URL '%s', expected e.g. https://api.example.com", apigw_base)
+		}
+		client.baseURL = strings.TrimSuffix(apigw_base, "/")
 		return nil
 	}
 }
@@ -91,9 +106,9 @@ func (c *httpClient) NewRequest(method string, path string, body io.Reader) (*ht
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
 
-	// Add custom headers to request
+	// Add custom headers to request, replacing the default ones
 	for k, v := range c.headers {
-		req.Header.Add(k, v)
+		req.Header.Set(k, v)
 	}
 
 	return req, nil
@@ -115,17 +130,76 @@ func (c *httpClient) NewRequestWithContext(ctx context.Context, method string, p
 	req.Header.Add("Accept", "application/json")
 	req.Header.Add("Content-Type", "application/json")
 
-	// Add custom headers to request
+	// Add custom headers to request, replacing the default ones
 	for k, v := range c.headers {
-		req.Header.Add(k, v)
+		req.Header.Set(k, v)
 	}
 
 	return req, nil
 }
 
-// Do executes a HTTP request.
+// Do executes a HTTP request, retrying it according to the client's retry policy.
 func (c *httpClient) Do(req *http.Request) (*http.Response, error) {
-	return c.client.Do(req)
+	if c.offline {
+		return nil, ErrOffline
+	}
+
+	attempts := max(c.retry.MaxAttempts, 1)
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			req.Body = body
+		}
+
+		if c.limiter != nil {
+			if err := c.limiter.Wait(req.Context()); err != nil {
+				return nil, err
+			}
+		}
+
+		logger.Debug("API Request: %s %s (attempt %d/%d)", req.Method, req.URL, attempt, attempts)
+		resp, err := c.client.Do(req)
+		if c.limiter != nil && err == nil {
+			if resp.StatusCode == http.StatusTooManyRequests {
+				c.limiter.throttle()
+				logger.Debug("API rate limit lowered to %.2f requests/s", c.limiter.Rate())
+			} else if resp.StatusCode < 400 {
+				c.limiter.relax()
+			}
+		}
+
+		if attempt >= attempts || !c.retry.shouldRetry(req, resp, err) {
+			if err == nil {
+				logger.Debug("API Response: %d %s (attempt %d/%d)", resp.StatusCode, req.URL, attempt, attempts)
+			}
+			return resp, err
+		}
+
+		delay, ok := c.retry.delay(attempt, resp)
+		if !ok {
+			logger.Debug("API Request: %s %s not retried, server asked to wait %s", req.Method, req.URL, delay)
+			return resp, err
+		}
+
+		if err != nil {
+			logger.Debug("API Request: %s %s failed: %v, retrying in %s", req.Method, req.URL, err, delay)
+		} else {
+			logger.Debug("API Request: %s %s returned %d, retrying in %s", req.Method, req.URL, resp.StatusCode, delay)
+			discardBody(resp)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
 }
 
 // DoRequest makes an HTTP request and executes it.
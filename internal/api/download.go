@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ErrRangeIgnored is returned when resuming a download and the server doesn't send
+// the requested range. Nothing is written, the download must restart from the beginning.
+var ErrRangeIgnored = errors.New("server does not support resuming the download")
+
+// SizeReceiver is implemented by writers which want the size of a download before
+// it starts, e.g. to show progress. offset is where the download resumes, and total
+// the size of the complete content, or -1 when the server doesn't send it.
+type SizeReceiver interface {
+	ExpectSize(offset, total int64)
+}
+
+// WriteScreenshot streams the screenshot of a report to w. Returns the number of bytes written.
+func (api *httpClient) WriteScreenshot(ctx context.Context, report_id string, w io.Writer) (int64, error) {
+	return api.download(ctx, fmt.Sprintf("/public/v1/report/%s/screenshot", report_id), w, 0)
+}
+
+// WriteDomainGraph streams the domain graph of a report to w. Returns the number of bytes written.
+func (api *httpClient) WriteDomainGraph(ctx context.Context, report_id string, w io.Writer) (int64, error) {
+	return api.download(ctx, fmt.Sprintf("/public/v1/report/%s/domain_graph", report_id), w, 0)
+}
+
+// WriteResource streams a resource to w. With an offset > 0 the download resumes at
+// that offset with a Range request, and ErrRangeIgnored is returned if the server
+// doesn't support it. Returns the number of bytes written.
+func (api *httpClient) WriteResource(ctx context.Context, report_id string, hash string, w io.Writer, offset int64) (int64, error) {
+	return api.download(ctx, fmt.Sprintf("/public/v1/report/%s/resource/%s", report_id, hash), w, offset)
+}
+
+func (api *httpClient) download(ctx context.Context, endpoint string, w io.Writer, offset int64) (int64, error) {
+	req, err := api.NewRequestWithContext(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := api.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	total := resp.ContentLength
+	if offset > 0 {
+		if resp.StatusCode != http.StatusPartialContent {
+			if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+				return 0, ErrRangeIgnored
+			}
+			return 0, handleResponseError(resp)
+		}
+
+		start, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok || start != offset {
+			return 0, ErrRangeIgnored
+		}
+		total = size
+	} else if err := handleResponseError(resp); err != nil {
+		return 0, err
+	}
+
+	if sr, ok := w.(SizeReceiver); ok {
+		sr.ExpectSize(offset, total)
+	}
+	return io.Copy(w, resp.Body)
+}
+
+// parseContentRange parses "bytes <start>-<end>/<size>", where size may be "*" (returned as -1)
+func parseContentRange(header string) (start int64, size int64, ok bool) {
+	spec, found := strings.CutPrefix(header, "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+
+	rng, total, found := strings.Cut(spec, "/")
+	first, _, found2 := strings.Cut(rng, "-")
+	if !found || !found2 {
+		return 0, 0, false
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	size = -1
+	if total != "*" {
+		if size, err = strconv.ParseInt(total, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, size, true
+}
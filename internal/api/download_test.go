@@ -0,0 +1,83 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sizeWriter records the size announced before a download
+type sizeWriter struct {
+	bytes.Buffer
+	offset, total int64
+}
+
+func (w *sizeWriter) ExpectSize(offset, total int64) {
+	w.offset, w.total = offset, total
+}
+
+func TestWriteResource(t *testing.T) {
+	content := strings.Repeat("0123456789", 100)
+	ranges := true
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ranges {
+			r.Header.Del("Range")
+		}
+		http.ServeContent(w, r, "resource", time.Time{}, strings.NewReader(content))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ApiGWBase(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	w := &sizeWriter{}
+	n, err := client.WriteResource(context.Background(), "r1", "abc", w, 0)
+	if err != nil || n != 1000 || w.String() != content || w.total != 1000 {
+		t.Fatalf("WriteResource() = %d, %v (total %d)", n, err, w.total)
+	}
+
+	// Resume after 400 bytes
+	w = &sizeWriter{}
+	n, err = client.WriteResource(context.Background(), "r1", "abc", w, 400)
+	if err != nil || n != 600 || w.String() != content[400:] {
+		t.Fatalf("Resumed WriteResource() = %d, %v", n, err)
+	}
+	if w.offset != 400 || w.total != 1000 {
+		t.Errorf("Expected offset 400 and total 1000, got %d and %d", w.offset, w.total)
+	}
+
+	// The server sends everything instead of the range
+	ranges = false
+	w = &sizeWriter{}
+	if _, err := client.WriteResource(context.Background(), "r1", "abc", w, 400); !errors.Is(err, ErrRangeIgnored) || w.Len() != 0 {
+		t.Errorf("Expected ErrRangeIgnored without writing, got %v (%d bytes)", err, w.Len())
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		header      string
+		start, size int64
+		ok          bool
+	}{
+		{"bytes 400-999/1000", 400, 1000, true},
+		{"bytes 0-9/*", 0, -1, true},
+		{"bytes */1000", 0, 0, false},
+		{"items 0-9/10", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		start, size, ok := parseContentRange(tt.header)
+		if start != tt.start || size != tt.size || ok != tt.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v", tt.header, start, size, ok)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket shared by all requests made through a client.
+//
+// When the server answers 429 the rate is halved (down to 1/16 of the configured
+// rate), and it recovers gradually with every successful response.
+type rateLimiter struct {
+	mu sync.Mutex
+
+	rate    float64 // Current tokens per second
+	maxRate float64 // Configured tokens per second
+	minRate float64
+	burst   float64
+	tokens  float64
+	last    time.Time
+}
+
+// Client side rate limit, in requests per second with a burst size
+func RateLimit(rps float64, burst int) OptionsClientFunc {
+	return func(client *httpClient) error {
+		if rps <= 0 {
+			return fmt.Errorf("rate limit must be greater than 0, got %v", rps)
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		client.limiter = newRateLimiter(rps, burst)
+		return nil
+	}
+}
+
+func newRateLimiter(rps float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rps,
+		maxRate: rps,
+		minRate: rps / 16,
+		burst:   float64(burst),
+		tokens:  float64(burst),
+		last:    time.Now(),
+	}
+}
+
+// refill adds the tokens accumulated since the last call. Must be called with mu held.
+func (l *rateLimiter) refill(now time.Time) {
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+}
+
+// Wait blocks until a request is allowed or the context is done
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		l.refill(time.Now())
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// throttle lowers the rate after the server answered 429
+func (l *rateLimiter) throttle() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(time.Now())
+	l.rate = max(l.rate/2, l.minRate)
+	l.tokens = 0
+}
+
+// relax raises the rate towards the configured rate after a successful response
+func (l *rateLimiter) relax() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate < l.maxRate {
+		l.refill(time.Now())
+		l.rate = min(l.rate+l.maxRate/20, l.maxRate)
+	}
+}
+
+// Rate returns the current number of requests allowed per second
+func (l *rateLimiter) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
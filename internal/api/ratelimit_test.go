@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	l := newRateLimiter(100, 2)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+
+	// 2 requests from the burst, then 4 at 100/s
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Errorf("Expected requests to be throttled, took %s", elapsed)
+	}
+}
+
+func TestRateLimiterContext(t *testing.T) {
+	l := newRateLimiter(0.1, 1)
+	l.Wait(context.Background()) // Use the burst
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := l.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestRateLimiterAdapts(t *testing.T) {
+	l := newRateLimiter(16, 1)
+
+	for i := 0; i < 10; i++ {
+		l.throttle()
+	}
+	if l.Rate() != 1 {
+		t.Errorf("Expected rate to be lowered to 1/16, got %v", l.Rate())
+	}
+
+	for i := 0; i < 100; i++ {
+		l.relax()
+	}
+	if l.Rate() != 16 {
+		t.Errorf("Expected rate to recover to 16, got %v", l.Rate())
+	}
+}
+
+func TestRateLimitOn429(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ApiGWBase(server.URL), RateLimit(1000, 10))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	resp, err := client.DoRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatalf("DoRequest() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if rate := client.limiter.Rate(); rate != 500 {
+		t.Errorf("Expected rate to be halved to 500, got %v", rate)
+	}
+}
+
+func TestRateLimitInvalid(t *testing.T) {
+	if _, err := NewClient(RateLimit(0, 1)); err == nil {
+		t.Error("Expected error for a rate of 0")
+	}
+}
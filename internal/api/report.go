@@ -1,50 +1,61 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"io"
 )
 
-func GetReport(report_id string) {
-	DefaultClient.GetReport(report_id)
-
+func GetReport(ctx context.Context, report_id string) (*Report, error) {
+	return DefaultClient.GetReport(ctx, report_id)
 }
 
-func (api httpClient) GetReport(report_id string) (*Report, error) {
+// GetReport returns a report, from the client's report cache when it is there
+func (api *httpClient) GetReport(ctx context.Context, report_id string) (*Report, error) {
 	var reply Report
 
+	if r, ok := api.cachedReport(report_id); ok {
+		return r, nil
+	}
+	if api.offline {
+		return nil, fmt.Errorf("report %s is not cached: %w", report_id, ErrOffline)
+	}
+
 	endpoint := fmt.Sprintf("/public/v1/report/%s", report_id)
-	resp, err := api.DoRequest("GET", endpoint, nil)
+	resp, err := api.DoRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	err = DecodeResponse(resp, &reply)
+	if err == nil {
+		api.storeReport(&reply)
+	}
 	return &reply, err
 }
 
-func (api httpClient) GetScreenshot(report_id string) ([]byte, error) {
+func GetScreenshot(ctx context.Context, report_id string) ([]byte, error) {
+	return DefaultClient.GetScreenshot(ctx, report_id)
+}
 
-	endpoint := fmt.Sprintf("/public/v1/report/%s/screenshot", report_id)
-	resp, err := api.DoRequest("GET", endpoint, nil)
+func (api *httpClient) GetScreenshot(ctx context.Context, report_id string) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := api.WriteScreenshot(ctx, report_id, &buf)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	data, err := io.ReadAll(resp.Body)
-	return data, err
+	return buf.Bytes(), nil
 }
 
-func (api httpClient) GetDomainGraph(report_id string) ([]byte, error) {
+func GetDomainGraph(ctx context.Context, report_id string) ([]byte, error) {
+	return DefaultClient.GetDomainGraph(ctx, report_id)
+}
 
-	endpoint := fmt.Sprintf("/public/v1/report/%s/domain_graph", report_id)
-	resp, err := api.DoRequest("GET", endpoint, nil)
+func (api *httpClient) GetDomainGraph(ctx context.Context, report_id string) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := api.WriteDomainGraph(ctx, report_id, &buf)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	data, err := io.ReadAll(resp.Body)
-	return data, err
+	return buf.Bytes(), nil
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 )
@@ -10,15 +11,15 @@ type ReputationResult struct {
 	Verdict string `json:"verdict"`
 }
 
-func CheckReputation(query string) (*ReputationResult, error) {
-	return DefaultClient.CheckReputation(query)
+func CheckReputation(ctx context.Context, query string) (*ReputationResult, error) {
+	return DefaultClient.CheckReputation(ctx, query)
 }
 
-func (api httpClient) CheckReputation(query string) (*ReputationResult, error) {
+func (api *httpClient) CheckReputation(ctx context.Context, query string) (*ReputationResult, error) {
 	var reply ReputationResult
 
 	endpoint := fmt.Sprintf("/public/v1/reputation/check/?query=%s", url.QueryEscape(query))
-	resp, err := api.DoRequest("GET", endpoint, nil)
+	resp, err := api.DoRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
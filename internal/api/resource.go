@@ -1,23 +1,19 @@
 package api
 
 import (
-	"fmt"
-	"io"
+	"bytes"
+	"context"
 )
 
-func GetResource(report_id string, hash string) {
-	DefaultClient.GetResource(report_id, hash)
+func GetResource(ctx context.Context, report_id string, hash string) ([]byte, error) {
+	return DefaultClient.GetResource(ctx, report_id, hash)
 }
 
-func (api httpClient) GetResource(report_id string, hash string) ([]byte, error) {
-
-	endpoint := fmt.Sprintf("/public/v1/report/%s/resource/%s", report_id, hash)
-	resp, err := api.DoRequest("GET", endpoint, nil)
+func (api *httpClient) GetResource(ctx context.Context, report_id string, hash string) ([]byte, error) {
+	var buf bytes.Buffer
+	_, err := api.WriteResource(ctx, report_id, hash, &buf, 0)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	data, err := io.ReadAll(resp.Body)
-	return data, err
+	return buf.Bytes(), nil
 }
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed requests are retried.
+//
+// Requests are retried on transport errors and on HTTP 429, 502, 503 and 504.
+// Only idempotent requests (GET, HEAD, OPTIONS, PUT, DELETE) are retried, with the
+// exception of 429 which means the request was never processed by the server.
+type RetryPolicy struct {
+	MaxAttempts int           // Total number of attempts, including the first. 1 or less disables retries
+	BaseDelay   time.Duration // Delay before the first retry, doubled for every following retry
+	MaxDelay    time.Duration // Upper bound of the delay between two attempts
+	Jitter      float64       // Fraction (0-1) of the delay which is randomized
+}
+
+// DefaultRetryPolicy is a sensible policy for interactive use and bulk jobs
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+	Jitter:      0.2,
+}
+
+// Retry policy for failed requests
+func Retry(policy RetryPolicy) OptionsClientFunc {
+	return func(client *httpClient) error {
+		if policy.Jitter < 0 || policy.Jitter > 1 {
+			return fmt.Errorf("retry jitter must be between 0 and 1, got %v", policy.Jitter)
+		}
+		if policy.BaseDelay < 0 || policy.MaxDelay < 0 {
+			return fmt.Errorf("retry delays must not be negative")
+		}
+		client.retry = policy
+		return nil
+	}
+}
+
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// shouldRetry reports if a request should be attempted again given the outcome of the last attempt
+func (p RetryPolicy) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil {
+		return false
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false // Body can't be replayed
+	}
+
+	if err != nil {
+		return idempotentMethods[req.Method]
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return idempotentMethods[req.Method]
+	}
+	return false
+}
+
+// delay returns how long to wait before the next attempt. The server's Retry-After or
+// rate limit reset headers are used when present, otherwise exponential backoff.
+// ok is false when the server asks for a longer delay than MaxDelay.
+func (p RetryPolicy) delay(attempt int, resp *http.Response) (d time.Duration, ok bool) {
+	if d, found := serverDelay(resp, time.Now()); found {
+		if p.MaxDelay > 0 && d > p.MaxDelay {
+			return d, false
+		}
+		return d, true
+	}
+
+	d = time.Duration(float64(p.BaseDelay) * math.Pow(2, float64(attempt-1)))
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if p.Jitter > 0 {
+		d = time.Duration(float64(d) * (1 + p.Jitter*(2*rand.Float64()-1)))
+	}
+	return d, true
+}
+
+// serverDelay parses Retry-After (seconds or HTTP date), or the reset time of an
+// exhausted rate limit (RateLimit-Reset / X-RateLimit-Reset, in seconds or unix time).
+func serverDelay(resp *http.Response, now time.Time) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second, true
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(t.Sub(now), 0), true
+		}
+	}
+
+	for _, prefix := range []string{"RateLimit-", "X-RateLimit-"} {
+		if resp.Header.Get(prefix+"Remaining") != "0" {
+			continue
+		}
+		reset, err := strconv.ParseInt(resp.Header.Get(prefix+"Reset"), 10, 64)
+		if err != nil || reset < 0 {
+			continue
+		}
+		if reset > 1_000_000_000 { // Unix timestamp
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+		return time.Duration(reset) * time.Second, true
+	}
+
+	return 0, false
+}
+
+// discardBody drains and closes the body, so the connection can be reused
+func discardBody(resp *http.Response) {
+	if resp != nil && resp.Body != nil {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		resp.Body.Close()
+	}
+}
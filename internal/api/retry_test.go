@@ -0,0 +1,191 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Millisecond,
+	MaxDelay:    50 * time.Millisecond,
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(ApiGWBase(server.URL), Retry(testRetryPolicy))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	resp, err := client.DoRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatalf("DoRequest() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status code %d, got %d", http.StatusOK, resp.StatusCode)
+	}
+	if calls != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(ApiGWBase(server.URL), Retry(testRetryPolicy))
+
+	resp, err := client.DoRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatalf("DoRequest() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadGateway, resp.StatusCode)
+	}
+	if calls != testRetryPolicy.MaxAttempts {
+		t.Errorf("Expected %d attempts, got %d", testRetryPolicy.MaxAttempts, calls)
+	}
+}
+
+func TestRetryNonIdempotent(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		if r.URL.Path == "/throttled" && len(bodies) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(ApiGWBase(server.URL), Retry(testRetryPolicy))
+
+	// POST is not retried on 503
+	resp, err := client.DoRequest("POST", "/unavailable", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("DoRequest() error = %v", err)
+	}
+	resp.Body.Close()
+	if len(bodies) != 1 {
+		t.Errorf("Expected 1 attempt for POST on 503, got %d", len(bodies))
+	}
+
+	// POST is retried on 429, with the body replayed
+	bodies = nil
+	resp, err = client.DoRequest("POST", "/throttled", strings.NewReader("payload"))
+	if err != nil {
+		t.Fatalf("DoRequest() error = %v", err)
+	}
+	resp.Body.Close()
+	if len(bodies) != 2 || bodies[1] != "payload" {
+		t.Errorf("Expected POST on 429 to be retried with body, got %q", bodies)
+	}
+}
+
+func TestRetryAfterTooLong(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(ApiGWBase(server.URL), Retry(testRetryPolicy))
+
+	resp, err := client.DoRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatalf("DoRequest() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if calls != 1 {
+		t.Errorf("Expected no retry when Retry-After exceeds MaxDelay, got %d attempts", calls)
+	}
+}
+
+func TestRetryContextCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	policy := testRetryPolicy
+	policy.BaseDelay = time.Second
+	policy.MaxDelay = time.Second
+	client, _ := NewClient(ApiGWBase(server.URL), Retry(policy))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := client.DoRequestWithContext(ctx, "GET", "/test", nil)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestServerDelay(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    time.Duration
+		found   bool
+	}{
+		{"none", nil, 0, false},
+		{"retry-after seconds", map[string]string{"Retry-After": "7"}, 7 * time.Second, true},
+		{"retry-after date", map[string]string{"Retry-After": now.Add(time.Minute).Format(http.TimeFormat)}, time.Minute, true},
+		{"rate limit remaining", map[string]string{"X-RateLimit-Remaining": "5", "X-RateLimit-Reset": "10"}, 0, false},
+		{"rate limit reset seconds", map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": "10"}, 10 * time.Second, true},
+		{"rate limit reset unix", map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1748779230"}, 30 * time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{Header: http.Header{}}
+			for k, v := range tt.headers {
+				resp.Header.Set(k, v)
+			}
+
+			got, found := serverDelay(resp, now)
+			if got != tt.want || found != tt.found {
+				t.Errorf("serverDelay() = %v, %v, want %v, %v", got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestRetryInvalidPolicy(t *testing.T) {
+	if _, err := NewClient(Retry(RetryPolicy{Jitter: 2})); err == nil {
+		t.Error("Expected error for jitter > 1")
+	}
+}
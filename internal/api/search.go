@@ -1,18 +1,20 @@
 package api
 
 import (
+	"context"
 	"fmt"
+	"net/url"
 )
 
-func Search(query string, limit int, offset int) (*SearchReportResponse, error) {
-	return DefaultClient.Search(query, limit, offset)
+func Search(ctx context.Context, query string, limit int, offset int) (*SearchReportResponse, error) {
+	return DefaultClient.Search(ctx, query, limit, offset)
 }
 
-func (api httpClient) Search(query string, limit int, offset int) (*SearchReportResponse, error) {
+func (api *httpClient) Search(ctx context.Context, query string, limit int, offset int) (*SearchReportResponse, error) {
 	var reply SearchReportResponse
 
-	endpoint := fmt.Sprintf("/public/v1/search/reports/?query=%s&limit=%d&offset=%d", query, limit, offset)
-	resp, err := api.DoRequest("GET", endpoint, nil)
+	endpoint := fmt.Sprintf("/public/v1/search/reports/?query=%s&limit=%d&offset=%d", url.QueryEscape(query), limit, offset)
+	resp, err := api.DoRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -20,3 +22,114 @@ func (api httpClient) Search(query string, limit int, offset int) (*SearchReport
 	err = DecodeResponse(resp, &reply)
 	return &reply, err
 }
+
+// Searcher is implemented by clients which can search reports
+type Searcher interface {
+	Search(ctx context.Context, query string, limit int, offset int) (*SearchReportResponse, error)
+}
+
+// SearchIterator walks through all the results of a search, fetching pages lazily
+// as the results are consumed.
+//
+//	it := api.NewSearchIterator(ctx, client, "domain:example.com", 100)
+//	for it.Next() {
+//		report := it.Report()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type SearchIterator struct {
+	ctx      context.Context
+	client   Searcher
+	query    string
+	pageSize int
+	offset   int
+	max      int
+
+	page    []ReportOverview
+	index   int
+	count   int
+	total   int
+	fetched bool
+	done    bool
+	err     error
+}
+
+func NewSearchIterator(ctx context.Context, client Searcher, query string, pageSize int) *SearchIterator {
+	if pageSize < 1 {
+		pageSize = 10
+	}
+	return &SearchIterator{
+		ctx:      ctx,
+		client:   client,
+		query:    query,
+		pageSize: pageSize,
+	}
+}
+
+// Offset sets the offset of the first result
+func (it *SearchIterator) Offset(offset int) *SearchIterator {
+	it.offset = offset
+	return it
+}
+
+// Max sets the maximum number of results returned by the iterator, 0 for no limit
+func (it *SearchIterator) Max(max int) *SearchIterator {
+	it.max = max
+	return it
+}
+
+// Next advances to the next result, fetching the next page if needed.
+// Returns false when there are no more results or an error occurred.
+func (it *SearchIterator) Next() bool {
+	if it.done || (it.max > 0 && it.count >= it.max) {
+		return false
+	}
+
+	if it.index >= len(it.page) {
+		if it.fetched && (len(it.page) < it.pageSize || it.offset >= it.total) {
+			it.done = true
+			return false
+		}
+
+		reply, err := it.client.Search(it.ctx, it.query, it.pageSize, it.offset)
+		if err != nil {
+			it.err = err
+			it.done = true
+			return false
+		}
+
+		it.fetched = true
+		it.total = reply.TotalHits
+		it.page = reply.Reports
+		it.index = 0
+		it.offset += len(reply.Reports)
+
+		if len(it.page) == 0 {
+			it.done = true
+			return false
+		}
+	}
+
+	it.index++
+	it.count++
+	return true
+}
+
+// Report returns the current result
+func (it *SearchIterator) Report() *ReportOverview {
+	if it.index == 0 || it.index > len(it.page) {
+		return nil
+	}
+	return &it.page[it.index-1]
+}
+
+// TotalHits returns the total number of hits reported by the last fetched page
+func (it *SearchIterator) TotalHits() int {
+	return it.total
+}
+
+// Err returns the error which stopped the iteration, if any
+func (it *SearchIterator) Err() error {
+	return it.err
+}
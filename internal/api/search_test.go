@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeSearcher serves a fixed number of hits
+type fakeSearcher struct {
+	hits  int
+	calls int
+	err   error
+}
+
+func (f *fakeSearcher) Search(ctx context.Context, query string, limit int, offset int) (*SearchReportResponse, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	reply := &SearchReportResponse{Query: query, TotalHits: f.hits, Limit: limit, Offset: offset}
+	for i := offset; i < offset+limit && i < f.hits; i++ {
+		reply.Reports = append(reply.Reports, ReportOverview{ID: fmt.Sprintf("report-%d", i)})
+	}
+	return reply, nil
+}
+
+func TestSearchIterator(t *testing.T) {
+	tests := []struct {
+		name      string
+		hits      int
+		pageSize  int
+		offset    int
+		max       int
+		wantCount int
+		wantCalls int
+	}{
+		{"empty", 0, 10, 0, 0, 0, 1},
+		{"single page", 5, 10, 0, 0, 5, 1},
+		{"exact pages", 20, 10, 0, 0, 20, 2},
+		{"partial last page", 25, 10, 0, 0, 25, 3},
+		{"with offset", 25, 10, 20, 0, 5, 1},
+		{"with max", 25, 10, 0, 12, 12, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searcher := &fakeSearcher{hits: tt.hits}
+			it := NewSearchIterator(context.Background(), searcher, "test", tt.pageSize).Offset(tt.offset).Max(tt.max)
+
+			count := 0
+			for it.Next() {
+				want := fmt.Sprintf("report-%d", tt.offset+count)
+				if it.Report().ID != want {
+					t.Errorf("Expected %s, got %s", want, it.Report().ID)
+				}
+				count++
+			}
+
+			if it.Err() != nil {
+				t.Errorf("Unexpected error: %v", it.Err())
+			}
+			if count != tt.wantCount {
+				t.Errorf("Expected %d results, got %d", tt.wantCount, count)
+			}
+			if searcher.calls != tt.wantCalls {
+				t.Errorf("Expected %d calls, got %d", tt.wantCalls, searcher.calls)
+			}
+		})
+	}
+}
+
+func TestSearchIteratorError(t *testing.T) {
+	searcher := &fakeSearcher{err: errors.New("boom")}
+	it := NewSearchIterator(context.Background(), searcher, "test", 10)
+
+	if it.Next() {
+		t.Error("Expected Next() to return false")
+	}
+	if it.Err() == nil {
+		t.Error("Expected an error")
+	}
+}
+
+func TestSearchEscapesQuery(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query().Get("query")
+		w.Write([]byte(`{"total_hits": 0}`))
+	}))
+	defer server.Close()
+
+	client, _ := NewClient(ApiGWBase(server.URL))
+	if _, err := client.Search(context.Background(), "domain:example.com & tag:phishing", 10, 0); err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	if query != "domain:example.com & tag:phishing" {
+		t.Errorf("Query not escaped correctly, got %q", query)
+	}
+}
@@ -0,0 +1,23 @@
+package api
+
+import (
+	"context"
+	"io"
+)
+
+// Service is the urlquery API: submissions, reports and their files, search and
+// reputation. The client returned by NewClient implements it; commands and tests
+// depend on the interface, so a fake can be used instead of the API.
+type Service interface {
+	Submit(ctx context.Context, submit SubmitJob) (*QueuedJob, error)
+	QueueStatus(ctx context.Context, queue_id string) (*QueuedJob, error)
+	WaitForReport(ctx context.Context, queue_id string, onStatus func(*QueuedJob)) (*QueuedJob, error)
+	GetReport(ctx context.Context, report_id string) (*Report, error)
+	WriteScreenshot(ctx context.Context, report_id string, w io.Writer) (int64, error)
+	WriteDomainGraph(ctx context.Context, report_id string, w io.Writer) (int64, error)
+	WriteResource(ctx context.Context, report_id string, hash string, w io.Writer, offset int64) (int64, error)
+	Search(ctx context.Context, query string, limit int, offset int) (*SearchReportResponse, error)
+	CheckReputation(ctx context.Context, query string) (*ReputationResult, error)
+}
+
+var _ Service = (*httpClient)(nil)
@@ -1,19 +1,20 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"strings"
 )
 
-func Submit(submit SubmitJob) (*QueuedJob, error) {
-	return DefaultClient.Submit(submit)
+func Submit(ctx context.Context, submit SubmitJob) (*QueuedJob, error) {
+	return DefaultClient.Submit(ctx, submit)
 }
 
-func (api httpClient) Submit(submit SubmitJob) (*QueuedJob, error) {
+func (api *httpClient) Submit(ctx context.Context, submit SubmitJob) (*QueuedJob, error) {
 	var queued_job QueuedJob
 
 	endpoint := "/public/v1/submit/url"
-	resp, err := api.DoRequest("POST", endpoint, strings.NewReader(submit.String()))
+	resp, err := api.DoRequestWithContext(ctx, "POST", endpoint, strings.NewReader(submit.String()))
 	if err != nil {
 		return nil, err
 	}
@@ -22,15 +23,15 @@ func (api httpClient) Submit(submit SubmitJob) (*QueuedJob, error) {
 	return &queued_job, err
 }
 
-func QueueStatus(queue_id string) (*QueuedJob, error) {
-	return DefaultClient.QueueStatus(queue_id)
+func QueueStatus(ctx context.Context, queue_id string) (*QueuedJob, error) {
+	return DefaultClient.QueueStatus(ctx, queue_id)
 }
 
-func (api httpClient) QueueStatus(queue_id string) (*QueuedJob, error) {
+func (api *httpClient) QueueStatus(ctx context.Context, queue_id string) (*QueuedJob, error) {
 	var queued_job QueuedJob
 
 	endpoint := fmt.Sprintf("/public/v1/submit/status/%s", queue_id)
-	resp, err := api.DoRequest("GET", endpoint, nil)
+	resp, err := api.DoRequestWithContext(ctx, "GET", endpoint, nil)
 	if err != nil {
 		return nil, err
 	}
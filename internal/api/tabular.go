@@ -0,0 +1,93 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Columns and rows used by the csv and table output formats
+
+func (j QueuedJob) Columns() []string {
+	return []string{"queue_id", "report_id", "status", "url", "access"}
+}
+
+func (j QueuedJob) Rows() [][]string {
+	return [][]string{{j.QueueID, j.ReportID, j.Status, j.Url.Addr, j.Access}}
+}
+
+func (r ReportOverview) Columns() []string {
+	return []string{"report_id", "date", "url", "final_url", "title", "ip", "country",
+		"alerts_urlquery", "alerts_ids", "alerts_analyzer", "tags"}
+}
+
+func (r ReportOverview) Rows() [][]string {
+	return [][]string{{
+		r.ID,
+		r.Date,
+		r.Url.Addr,
+		r.Final.Url.Addr,
+		r.Final.Title,
+		r.Ip.Addr,
+		r.Ip.CountryCode,
+		strconv.Itoa(r.Stats.AlertCount.Urlquery),
+		strconv.Itoa(r.Stats.AlertCount.Ids),
+		strconv.Itoa(r.Stats.AlertCount.Analyzer),
+		strings.Join(r.Tags, " "),
+	}}
+}
+
+func (r Report) Columns() []string {
+	return []string{"report_id", "date", "url", "final_url", "title", "ip", "asn", "country",
+		"alerts_urlquery", "alerts_ids", "alerts_analyzer", "tags"}
+}
+
+// Rows returns a single summary row of the report
+func (r Report) Rows() [][]string {
+	asn := ""
+	if r.Ip.ASN != 0 {
+		asn = "AS" + strconv.Itoa(r.Ip.ASN)
+	}
+	return [][]string{{
+		r.ID,
+		r.Date,
+		r.Url.Addr,
+		r.Final.Url.Addr,
+		r.Final.Title,
+		r.Ip.Addr,
+		asn,
+		r.Ip.CountryCode,
+		strconv.Itoa(r.Stats.AlertCount.Urlquery),
+		strconv.Itoa(r.Stats.AlertCount.Ids),
+		strconv.Itoa(r.Stats.AlertCount.Analyzer),
+		strings.Join(r.Tags, " "),
+	}}
+}
+
+func (sr *SearchReportResponse) Columns() []string {
+	return ReportOverview{}.Columns()
+}
+
+func (sr *SearchReportResponse) Rows() [][]string {
+	var rows [][]string
+	for _, r := range sr.Reports {
+		rows = append(rows, r.Rows()...)
+	}
+	return rows
+}
+
+// Items returns the reports, which are written one per line in the ndjson format
+func (sr *SearchReportResponse) Items() []any {
+	items := make([]any, len(sr.Reports))
+	for i := range sr.Reports {
+		items[i] = &sr.Reports[i]
+	}
+	return items
+}
+
+func (r ReputationResult) Columns() []string {
+	return []string{"url", "verdict"}
+}
+
+func (r ReputationResult) Rows() [][]string {
+	return [][]string{{r.Url, r.Verdict}}
+}
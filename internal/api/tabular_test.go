@@ -0,0 +1,40 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/urlquery/urlquery-cli/internal/output"
+)
+
+func TestReportTabular(t *testing.T) {
+	r := &Report{}
+	r.ID = "r1"
+	r.Date = "2025-06-01T10:00:00Z"
+	r.Url.Addr = "example.com/"
+	r.Final.Url.Addr = "example.com/login"
+	r.Final.Title = "Sign in"
+	r.Ip = IP{Addr: "192.0.2.1", ASN: 64500, CountryCode: "NO"}
+	r.Stats.AlertCount.Urlquery = 2
+	r.Tags = []string{"phishing", "kit"}
+
+	f, err := output.New("csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Format(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "report_id,date,url,final_url,title,ip,asn,country,alerts_urlquery,alerts_ids,alerts_analyzer,tags\n" +
+		"r1,2025-06-01T10:00:00Z,example.com/,example.com/login,Sign in,192.0.2.1,AS64500,NO,2,0,0,phishing kit\n"
+	if buf.String() != want {
+		t.Errorf("csv = %q, want %q", buf.String(), want)
+	}
+
+	f, _ = output.New("table")
+	if err := f.Format(&bytes.Buffer{}, r); err != nil {
+		t.Errorf("table: %v", err)
+	}
+}
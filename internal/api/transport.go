@@ -0,0 +1,96 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+// TransportConfig configures the connection to the API gateway, e.g. behind a
+// corporate proxy or to an on-prem gateway
+type TransportConfig struct {
+	Proxy      string // Proxy URL, instead of HTTPS_PROXY/HTTP_PROXY/NO_PROXY
+	CACert     string // PEM file of CA certificates trusted in addition to the system ones
+	ClientCert string // PEM file of the client certificate for mTLS
+	ClientKey  string // PEM file of the client key, if not in the certificate file
+}
+
+// NewTransport returns an HTTP transport with the proxy and TLS settings
+func NewTransport(cfg TransportConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if cfg.Proxy != "" {
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL '%s'", cfg.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no PEM certificates found in CA bundle %s", cfg.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.ClientCert != "" {
+		key := cfg.ClientKey
+		if key == "" {
+			key = cfg.ClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.ClientCert, key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else if cfg.ClientKey != "" {
+		return nil, fmt.Errorf("a client key needs a client certificate")
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// Transport sets the HTTP transport of the client, e.g. from NewTransport
+func Transport(rt http.RoundTripper) OptionsClientFunc {
+	return func(client *httpClient) error {
+		client.client.Transport = rt
+		return nil
+	}
+}
+
+// Timeout sets the maximum time of a request, including reading the response body.
+// 0 means no timeout.
+func Timeout(d time.Duration) OptionsClientFunc {
+	return func(client *httpClient) error {
+		if d < 0 {
+			return fmt.Errorf("invalid timeout %s", d)
+		}
+		client.client.Timeout = d
+		return nil
+	}
+}
+
+// Headers adds headers to every request, e.g. for an on-prem gateway
+func Headers(headers map[string]string) OptionsClientFunc {
+	return func(client *httpClient) error {
+		for k, v := range headers {
+			client.headers[http.CanonicalHeaderKey(k)] = v
+		}
+		return nil
+	}
+}
@@ -0,0 +1,186 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// newClientCert returns a CA, and a client certificate and key signed by it, as PEM files
+func newClientCert(t *testing.T, dir string) (ca *x509.Certificate, certFile, keyFile string) {
+	t.Helper()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, _ = x509.ParseCertificate(caDER)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "urlquery-cli"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, "client.pem")
+	keyFile = filepath.Join(dir, "client.key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return ca, certFile, keyFile
+}
+
+func get(t *testing.T, transport *http.Transport, url string) error {
+	t.Helper()
+	resp, err := (&http.Client{Transport: transport, Timeout: 5 * time.Second}).Get(url)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func TestNewTransportTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, certFile, keyFile := newClientCert(t, dir)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	srv.StartTLS()
+	defer srv.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", srv.Certificate().Raw)
+
+	// Server certificate not trusted
+	transport, err := NewTransport(TransportConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := get(t, transport, srv.URL); err == nil {
+		t.Error("untrusted server: no error")
+	}
+
+	// Trusted, but no client certificate
+	transport, err = NewTransport(TransportConfig{CACert: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := get(t, transport, srv.URL); err == nil {
+		t.Error("no client certificate: no error")
+	}
+
+	transport, err = NewTransport(TransportConfig{CACert: caFile, ClientCert: certFile, ClientKey: keyFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := get(t, transport, srv.URL); err != nil {
+		t.Errorf("mTLS: %v", err)
+	}
+}
+
+func TestNewTransportProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	transport, err := NewTransport(TransportConfig{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := get(t, transport, "http://api.example.invalid/public/v1/"); err != nil {
+		t.Fatal(err)
+	}
+	if proxied != "http://api.example.invalid/public/v1/" {
+		t.Errorf("proxied request = %q", proxied)
+	}
+}
+
+func TestNewTransportErrors(t *testing.T) {
+	dir := t.TempDir()
+	notPEM := filepath.Join(dir, "ca.pem")
+	os.WriteFile(notPEM, []byte("not a certificate"), 0600)
+
+	for name, cfg := range map[string]TransportConfig{
+		"invalid proxy":    {Proxy: "://"},
+		"missing CA":       {CACert: filepath.Join(dir, "missing.pem")},
+		"invalid CA":       {CACert: notPEM},
+		"invalid cert":     {ClientCert: notPEM},
+		"key without cert": {ClientKey: notPEM},
+	} {
+		if _, err := NewTransport(cfg); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	client, err := NewClient(Headers(map[string]string{"x-tenant": "soc", "User-Agent": "custom"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := client.NewRequest("GET", "/test", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("X-Tenant") != "soc" || req.Header.Values("User-Agent")[0] != "custom" || len(req.Header.Values("User-Agent")) != 1 {
+		t.Errorf("headers = %v", req.Header)
+	}
+}
+
+func TestApiGWBaseInvalid(t *testing.T) {
+	for _, base := range []string{"api.example.com", "ftp://api.example.com", "https://"} {
+		if _, err := NewClient(ApiGWBase(base)); err == nil {
+			t.Errorf("%s: no error", base)
+		}
+	}
+}
@@ -67,16 +67,19 @@ type ReportOverview struct {
 	} `json:"settings"`
 
 	Stats struct {
-		AlertCount struct {
-			Ids      int `json:"ids"`
-			Urlquery int `json:"urlquery"`
-			Analyzer int `json:"analyzer"`
-		} `json:"alert_count"`
+		AlertCount AlertCounts `json:"alert_count"`
 	} `json:"stats"`
 
 	Summary []ReportSummary `json:"summary"`
 }
 
+// AlertCounts are the alert counts of a report
+type AlertCounts struct {
+	Ids      int `json:"ids"`
+	Urlquery int `json:"urlquery"`
+	Analyzer int `json:"analyzer"`
+}
+
 type ReportSummary struct {
 	Fqdn string `json:"fqdn"`
 
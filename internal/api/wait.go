@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Queue statuses returned by the submit status endpoint
+const (
+	StatusQueued     = "queued"
+	StatusProcessing = "processing"
+	StatusAnalyzing  = "analyzing"
+	StatusDone       = "done"
+)
+
+// Polling intervals used by WaitForReport. The interval starts at WaitPollInterval
+// and grows by 50% for every poll, up to WaitMaxPollInterval.
+var (
+	WaitPollInterval    = 2 * time.Second
+	WaitMaxPollInterval = 15 * time.Second
+)
+
+// WaitForReport polls the queue with DefaultClient until the submission is done
+func WaitForReport(ctx context.Context, queue_id string, onStatus func(*QueuedJob)) (*QueuedJob, error) {
+	return DefaultClient.WaitForReport(ctx, queue_id, onStatus)
+}
+
+// WaitForReport polls the queue until the submission is done or the context is
+// cancelled. onStatus (optional) is called every time the status changes.
+func (api *httpClient) WaitForReport(ctx context.Context, queue_id string, onStatus func(*QueuedJob)) (*QueuedJob, error) {
+	interval := WaitPollInterval
+	last := ""
+
+	for {
+		job, err := api.QueueStatus(ctx, queue_id)
+		if ctx.Err() != nil {
+			// Cancelled during the request
+			return job, ctx.Err()
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if job.Status != last {
+			last = job.Status
+			if onStatus != nil {
+				onStatus(job)
+			}
+		}
+
+		if job.Status == StatusDone {
+			return job, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return job, ctx.Err()
+		case <-time.After(interval):
+		}
+
+		interval = interval * 3 / 2
+		if interval > WaitMaxPollInterval {
+			interval = WaitMaxPollInterval
+		}
+	}
+}
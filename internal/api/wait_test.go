@@ -0,0 +1,81 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fastPolling polls the queue every millisecond during the test
+func fastPolling(t *testing.T) {
+	interval, max := WaitPollInterval, WaitMaxPollInterval
+	t.Cleanup(func() {
+		WaitPollInterval, WaitMaxPollInterval = interval, max
+	})
+	WaitPollInterval = time.Millisecond
+	WaitMaxPollInterval = time.Millisecond
+}
+
+func TestWaitForReport(t *testing.T) {
+	statuses := []string{"queued", "queued", "processing", "analyzing", "done"}
+	polls := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := statuses[polls]
+		if polls < len(statuses)-1 {
+			polls++
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"queue_id": "q1", "report_id": "r1", "status": %q}`, status)
+	}))
+	defer server.Close()
+
+	fastPolling(t)
+
+	client, err := NewClient(ApiGWBase(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	var seen []string
+	job, err := client.WaitForReport(context.Background(), "q1", func(j *QueuedJob) {
+		seen = append(seen, j.Status)
+	})
+	if err != nil {
+		t.Fatalf("WaitForReport() error = %v", err)
+	}
+
+	if job.ReportID != "r1" {
+		t.Errorf("Expected report ID r1, got %s", job.ReportID)
+	}
+
+	want := []string{"queued", "processing", "analyzing", "done"}
+	if fmt.Sprint(seen) != fmt.Sprint(want) {
+		t.Errorf("Expected transitions %v, got %v", want, seen)
+	}
+}
+
+func TestWaitForReportTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"queue_id": "q1", "status": "queued"}`))
+	}))
+	defer server.Close()
+
+	fastPolling(t)
+
+	client, err := NewClient(ApiGWBase(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.WaitForReport(ctx, "q1", nil); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+	}
+}
@@ -0,0 +1,283 @@
+// Package bundle handles report bundles: a directory with the artifacts of a
+// report and a manifest.json listing them, optionally packed into an archive.
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/integrity"
+)
+
+const ManifestFile = "manifest.json"
+
+// Archive formats supported by Pack
+const (
+	FormatZip   = "zip"
+	FormatTarGz = "tar.gz"
+)
+
+// File is an artifact of the bundle. Name is relative to the bundle directory,
+// with forward slashes. Failed downloads are listed with the error.
+type File struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type Manifest struct {
+	ReportID string `json:"report_id"`
+	Created  string `json:"created"`
+	Files    []File `json:"files"`
+}
+
+// NewFile describes the content of a file
+func NewFile(name string, data []byte) File {
+	sum := sha256.Sum256(data)
+	return File{Name: name, Size: int64(len(data)), SHA256: hex.EncodeToString(sum[:])}
+}
+
+// HashFile describes a file of the bundle directory dir, given by its path
+func HashFile(dir, path string) (File, error) {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return File{Name: filepath.ToSlash(path)}, err
+	}
+	f := File{Name: filepath.ToSlash(rel)}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		return f, err
+	}
+	defer fd.Close()
+
+	h := integrity.NewHasher()
+	if _, err := io.Copy(h, fd); err != nil {
+		return f, err
+	}
+	f.Size, f.SHA256 = h.Size(), h.Digest().SHA256
+	return f, nil
+}
+
+func NewManifest(reportID string) *Manifest {
+	return &Manifest{ReportID: reportID, Created: time.Now().UTC().Format(time.RFC3339), Files: []File{}}
+}
+
+// Write saves the manifest in the bundle directory, with the files sorted by name
+func (m *Manifest) Write(dir string) error {
+	sort.Slice(m.Files, func(i, j int) bool { return m.Files[i].Name < m.Files[j].Name })
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ManifestFile), data, 0644)
+}
+
+// ReadManifest loads the manifest of a bundle directory
+func ReadManifest(dir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return m, nil
+}
+
+// Pack writes the bundle directory to an archive next to it (<dir>.zip or
+// <dir>.tar.gz), with the files under the directory name. Returns the archive path.
+func Pack(dir, format string) (string, error) {
+	dir = filepath.Clean(dir)
+
+	var write func(w io.Writer, dir string) error
+	switch format {
+	case FormatZip:
+		write = writeZip
+	case FormatTarGz:
+		write = writeTarGz
+	default:
+		return "", fmt.Errorf("unknown archive format '%s' (available: %s, %s)", format, FormatZip, FormatTarGz)
+	}
+
+	path := dir + "." + format
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+
+	if err := write(f, dir); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// walkFiles calls fn for every regular file of dir, with its name in the archive
+func walkFiles(dir string, fn func(path, name string, info fs.FileInfo) error) error {
+	base := filepath.Base(dir)
+	return filepath.Walk(dir, func(path string, info fs.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return fn(path, base+"/"+filepath.ToSlash(rel), info)
+	})
+}
+
+func writeZip(w io.Writer, dir string) error {
+	zw := zip.NewWriter(w)
+
+	err := walkFiles(dir, func(path, name string, info fs.FileInfo) error {
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = name
+		header.Method = zip.Deflate
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		return copyFile(fw, path)
+	})
+	if err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func writeTarGz(w io.Writer, dir string) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	err := walkFiles(dir, func(path, name string, info fs.FileInfo) error {
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = name
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		return copyFile(tw, path)
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+func copyFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// Verification statuses of the files of a bundle
+const (
+	StatusOK       = "ok"
+	StatusMissing  = "missing"
+	StatusMismatch = "mismatch"
+	StatusSkipped  = "skipped" // The download failed, so the file isn't in the bundle
+)
+
+// Check is the verification result of a file
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func (c Check) Columns() []string {
+	return []string{"name", "status", "detail"}
+}
+
+func (c Check) Rows() [][]string {
+	return [][]string{{c.Name, c.Status, c.Detail}}
+}
+
+// Verify checks the size and sha256 of every file listed in the manifest of a
+// bundle directory. Resources named after their hash (not quarantined) are checked
+// against it as well.
+func Verify(dir string) ([]Check, error) {
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]Check, 0, len(m.Files))
+	for _, f := range m.Files {
+		checks = append(checks, verifyFile(dir, f))
+	}
+	return checks, nil
+}
+
+func verifyFile(dir string, f File) Check {
+	c := Check{Name: f.Name, Status: StatusOK}
+	if f.Error != "" {
+		c.Status, c.Detail = StatusSkipped, f.Error
+		return c
+	}
+
+	fd, err := os.Open(filepath.Join(dir, filepath.FromSlash(f.Name)))
+	if err != nil {
+		c.Status, c.Detail = StatusMissing, err.Error()
+		if !os.IsNotExist(err) {
+			c.Status = StatusMismatch
+		}
+		return c
+	}
+	defer fd.Close()
+
+	h := integrity.NewHasher()
+	if _, err := io.Copy(h, fd); err != nil {
+		c.Status, c.Detail = StatusMismatch, err.Error()
+		return c
+	}
+
+	if h.Size() != f.Size {
+		c.Status, c.Detail = StatusMismatch, fmt.Sprintf("size mismatch: expected %d, got %d", f.Size, h.Size())
+		return c
+	}
+
+	d := h.Digest()
+	err = d.Verify(integrity.Digest{SHA256: f.SHA256})
+	if hash, ok := strings.CutPrefix(f.Name, "resources/"); ok && err == nil && !strings.Contains(hash, ".") {
+		err = d.Match(hash)
+	}
+	if err != nil {
+		c.Status, c.Detail = StatusMismatch, err.Error()
+	}
+	return c
+}
@@ -0,0 +1,153 @@
+package bundle
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+const helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+
+func testBundle(t *testing.T) string {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "82c4121d-d037-4d60-9f74-517bf00091ce")
+	if err := os.MkdirAll(filepath.Join(dir, "resources"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManifest("82c4121d-d037-4d60-9f74-517bf00091ce")
+	for name, data := range map[string]string{"report.json": "{}", "resources/" + helloSHA256: "hello"} {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		m.Files = append(m.Files, NewFile(name, []byte(data)))
+	}
+	m.Files = append(m.Files, File{Name: "screenshot.png", Error: "not found"})
+
+	if err := m.Write(dir); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	return dir
+}
+
+func TestManifest(t *testing.T) {
+	dir := testBundle(t)
+
+	m, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatalf("ReadManifest() error = %v", err)
+	}
+	if len(m.Files) != 3 || m.Files[0].Name != "report.json" || m.Files[2].Name != "screenshot.png" {
+		t.Fatalf("Unexpected files %+v", m.Files)
+	}
+
+	f := m.Files[1]
+	if f.Size != 5 || f.SHA256 != helloSHA256 {
+		t.Errorf("Unexpected file %+v", f)
+	}
+}
+
+func TestPack(t *testing.T) {
+	expected := []string{
+		"82c4121d-d037-4d60-9f74-517bf00091ce/manifest.json",
+		"82c4121d-d037-4d60-9f74-517bf00091ce/report.json",
+		"82c4121d-d037-4d60-9f74-517bf00091ce/resources/" + helloSHA256,
+	}
+
+	t.Run("zip", func(t *testing.T) {
+		path, err := Pack(testBundle(t), FormatZip)
+		if err != nil {
+			t.Fatalf("Pack() error = %v", err)
+		}
+
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer zr.Close()
+
+		var names []string
+		for _, f := range zr.File {
+			names = append(names, f.Name)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("Unexpected entries %v", names)
+		}
+	})
+
+	t.Run("tar.gz", func(t *testing.T) {
+		path, err := Pack(testBundle(t), FormatTarGz)
+		if err != nil {
+			t.Fatalf("Pack() error = %v", err)
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		tr := tar.NewReader(gr)
+		for {
+			h, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			names = append(names, h.Name)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("Unexpected entries %v", names)
+		}
+	})
+
+	if _, err := Pack(testBundle(t), "rar"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	dir := testBundle(t)
+
+	status := func() map[string]string {
+		checks, err := Verify(dir)
+		if err != nil {
+			t.Fatalf("Verify() error = %v", err)
+		}
+		out := map[string]string{}
+		for _, c := range checks {
+			out[c.Name] = c.Status
+		}
+		return out
+	}
+
+	expected := map[string]string{"report.json": StatusOK, "resources/" + helloSHA256: StatusOK, "screenshot.png": StatusSkipped}
+	if got := status(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Verify() = %v", got)
+	}
+
+	// Same size, different content
+	os.WriteFile(filepath.Join(dir, "resources", helloSHA256), []byte("hallo"), 0644)
+	os.Remove(filepath.Join(dir, "report.json"))
+
+	expected = map[string]string{"report.json": StatusMissing, "resources/" + helloSHA256: StatusMismatch, "screenshot.png": StatusSkipped}
+	if got := status(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Verify() = %v", got)
+	}
+}
@@ -0,0 +1,218 @@
+// Package cache stores completed reports on disk, so they can be read again without
+// the API. Reports are stored by ID and version as <dir>/<report_id>/v<version>.json.
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/google/uuid"
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+// Cache is a report cache in a directory. It implements api.ReportStore.
+type Cache struct {
+	dir string
+}
+
+// Entry is a cached report version
+type Entry struct {
+	ReportID string    `json:"report_id"`
+	Version  int       `json:"version"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Path     string    `json:"path"`
+}
+
+func (e Entry) Columns() []string {
+	return []string{"report_id", "version", "size", "modified"}
+}
+
+func (e Entry) Rows() [][]string {
+	return [][]string{{e.ReportID, strconv.Itoa(e.Version), humanize.Bytes(uint64(e.Size)), e.Modified.Format(time.RFC3339)}}
+}
+
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// DefaultDir returns the report cache directory in the user cache directory
+// ($XDG_CACHE_HOME or ~/.cache on Linux)
+func DefaultDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "urlquery-cli", "reports"), nil
+}
+
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// Get returns the latest cached version of a report
+func (c *Cache) Get(reportID string) (*api.Report, bool) {
+	versions, err := c.versions(reportID)
+	if err != nil || len(versions) == 0 {
+		return nil, false
+	}
+
+	r, err := Load(versions[len(versions)-1].Path)
+	if err != nil {
+		return nil, false
+	}
+	return r, true
+}
+
+// Put stores a report. The file is written atomically, so a concurrent reader
+// never sees a partial report.
+func (c *Cache) Put(r *api.Report) error {
+	if _, err := uuid.Parse(r.ID); err != nil {
+		return fmt.Errorf("invalid report ID '%s'", r.ID)
+	}
+
+	dir := filepath.Join(c.dir, r.ID)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, fmt.Sprintf("v%d.json", r.Version)))
+}
+
+// Load reads a cached report file
+func Load(path string) (*api.Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r api.Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("invalid report %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// List returns every cached report version, by report ID and version
+func (c *Cache) List() ([]Entry, error) {
+	dirs, err := os.ReadDir(c.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		versions, err := c.versions(d.Name())
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, versions...)
+	}
+	return entries, nil
+}
+
+// versions returns the cached versions of a report, oldest first
+func (c *Cache) versions(reportID string) ([]Entry, error) {
+	if _, err := uuid.Parse(reportID); err != nil {
+		return nil, nil
+	}
+
+	dir := filepath.Join(c.dir, reportID)
+	files, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasPrefix(name, "v") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		version, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "v"), ".json"))
+		if err != nil {
+			continue
+		}
+		info, err := f.Info()
+		if err != nil {
+			continue
+		}
+
+		entries = append(entries, Entry{
+			ReportID: reportID,
+			Version:  version,
+			Size:     info.Size(),
+			Modified: info.ModTime(),
+			Path:     filepath.Join(dir, name),
+		})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Version < entries[j].Version })
+	return entries, nil
+}
+
+// Prune removes the versions superseded by a newer version of the same report, and
+// the reports cached before the cutoff time (when not zero). Returns the removed entries.
+func (c *Cache) Prune(before time.Time) ([]Entry, error) {
+	entries, err := c.List()
+	if err != nil {
+		return nil, err
+	}
+
+	latest := make(map[string]int)
+	for _, e := range entries {
+		latest[e.ReportID] = max(latest[e.ReportID], e.Version)
+	}
+
+	var removed []Entry
+	for _, e := range entries {
+		if e.Version == latest[e.ReportID] && (before.IsZero() || !e.Modified.Before(before)) {
+			continue
+		}
+		if err := os.Remove(e.Path); err != nil {
+			return removed, err
+		}
+		os.Remove(filepath.Dir(e.Path)) // Only removed when empty
+		removed = append(removed, e)
+	}
+	return removed, nil
+}
+
+// Clear removes every cached report
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.dir)
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+const reportID = "82c4121d-d037-4d60-9f74-517bf00091ce"
+
+func TestPutGet(t *testing.T) {
+	c := New(t.TempDir())
+
+	if _, ok := c.Get(reportID); ok {
+		t.Fatalf("Unexpected cached report")
+	}
+
+	for _, version := range []int{1, 3, 2} {
+		r := &api.Report{}
+		r.ID, r.Version, r.Final.Title = reportID, version, "Sign in"
+		if err := c.Put(r); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	r, ok := c.Get(reportID)
+	if !ok || r.Version != 3 || r.Final.Title != "Sign in" {
+		t.Errorf("Expected version 3, got %+v", r)
+	}
+
+	r.ID = "../escape"
+	if err := c.Put(r); err == nil {
+		t.Errorf("Expected an error for an invalid report ID")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	c := New(t.TempDir())
+
+	for _, version := range []int{1, 2} {
+		r := &api.Report{}
+		r.ID, r.Version = reportID, version
+		c.Put(r)
+	}
+	other := &api.Report{}
+	other.ID = "902d9135-12fe-4e75-95bb-a6d1e8c79ed1"
+	c.Put(other)
+
+	// Superseded versions are removed
+	removed, err := c.Prune(time.Time{})
+	if err != nil || len(removed) != 1 || removed[0].Version != 1 {
+		t.Fatalf("Prune() = %+v, %v", removed, err)
+	}
+
+	// Reports cached before the cutoff are removed
+	old := time.Now().Add(-48 * time.Hour)
+	entries, _ := c.List()
+	for _, e := range entries {
+		if e.ReportID == reportID {
+			os.Chtimes(e.Path, old, old)
+		}
+	}
+	removed, err = c.Prune(time.Now().Add(-24 * time.Hour))
+	if err != nil || len(removed) != 1 || removed[0].ReportID != reportID {
+		t.Fatalf("Prune() = %+v, %v", removed, err)
+	}
+
+	entries, _ = c.List()
+	if len(entries) != 1 || entries[0].ReportID != other.ID {
+		t.Errorf("Unexpected entries %+v", entries)
+	}
+}
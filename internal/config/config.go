@@ -0,0 +1,278 @@
+// Package config edits the urlquery-cli config file. Settings are read with viper,
+// but edits are made to the file content only, so that flag, environment and
+// profile values are never written back to the file.
+//
+// Profiles are named sets of settings stored under the profiles key, which override
+// the top level settings when the profile is used:
+//
+//	apikey: personal-key
+//	profile: team          # Profile used by default
+//	profiles:
+//	  team:
+//	    apikey: team-key
+//	  staging:
+//	    apikey: staging-key
+//	    apigw_base: https://staging.example.com
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// KeyProfile is the profile used when none is given with --profile or URLQUERY_PROFILE
+	KeyProfile = "profile"
+
+	// KeyProfiles holds the profiles, by name
+	KeyProfiles = "profiles"
+
+	// DefaultProfile is the name used to select the top level settings only
+	DefaultProfile = "default"
+)
+
+// ProfileKeys are the settings a profile can override
+var ProfileKeys = []string{
+	"apikey", "apikey_command", "apigw_base", "access", "useragent",
+	"proxy", "ca_cert", "client_cert", "client_key",
+}
+
+var profileName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidProfileName reports an error if the name can't be used for a profile. Names
+// are lower case, as viper keys are case insensitive.
+func ValidProfileName(name string) error {
+	if name == DefaultProfile {
+		return fmt.Errorf("'%s' is reserved for the settings outside of profiles", DefaultProfile)
+	}
+	if !profileName.MatchString(name) {
+		return fmt.Errorf("invalid profile name '%s': use lower case letters, digits, '-' and '_'", name)
+	}
+	return nil
+}
+
+// IsProfileKey reports if a profile can override the setting
+func IsProfileKey(key string) bool {
+	return contains(ProfileKeys, key)
+}
+
+// File is the content of a config file
+type File struct {
+	Path   string
+	Values map[string]any
+}
+
+// Load reads the config file. A missing file is empty.
+func Load(path string) (*File, error) {
+	f := &File{Path: path, Values: map[string]any{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return f, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var values map[any]any
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	f.Values = stringMap(values)
+	return f, nil
+}
+
+// Save writes the config file atomically. A new file is only readable by its owner.
+func (f *File) Save() error {
+	data, err := yaml.Marshal(f.Values)
+	if err != nil {
+		return err
+	}
+
+	mode := fs.FileMode(0600)
+	if info, err := os.Stat(f.Path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(f.Path), ".urlquery-cli-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.Path)
+}
+
+// Lookup returns a value by its dotted key, e.g. notify.thresholds
+func (f *File) Lookup(key string) (any, bool) {
+	var v any = f.Values
+	for _, part := range strings.Split(key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// Set sets a top level value
+func (f *File) Set(key string, value any) {
+	f.Values[key] = value
+}
+
+// Unset removes a top level value, and reports if it was set
+func (f *File) Unset(key string) bool {
+	_, ok := f.Values[key]
+	delete(f.Values, key)
+	return ok
+}
+
+// Profiles returns the profile names, sorted
+func (f *File) Profiles() []string {
+	profiles, _ := f.Values[KeyProfiles].(map[string]any)
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the settings of a profile
+func (f *File) Profile(name string) (map[string]any, bool) {
+	profiles, _ := f.Values[KeyProfiles].(map[string]any)
+	v, ok := profiles[name]
+	if !ok {
+		return nil, false
+	}
+	values, _ := v.(map[string]any)
+	if values == nil {
+		values = map[string]any{}
+	}
+	return values, true
+}
+
+// SetProfile replaces the settings of a profile, creating it if needed
+func (f *File) SetProfile(name string, values map[string]any) {
+	profiles, _ := f.Values[KeyProfiles].(map[string]any)
+	if profiles == nil {
+		profiles = map[string]any{}
+		f.Values[KeyProfiles] = profiles
+	}
+	profiles[name] = values
+}
+
+// RemoveProfile removes a profile, and reports if it existed. If it was the default
+// profile, the top level settings are used again by default.
+func (f *File) RemoveProfile(name string) bool {
+	profiles, _ := f.Values[KeyProfiles].(map[string]any)
+	if _, ok := profiles[name]; !ok {
+		return false
+	}
+	delete(profiles, name)
+	if len(profiles) == 0 {
+		delete(f.Values, KeyProfiles)
+	}
+	if f.Values[KeyProfile] == name {
+		delete(f.Values, KeyProfile)
+	}
+	return true
+}
+
+// stringMap converts the maps decoded by yaml.v2 to string keyed maps, as used by viper
+func stringMap(m map[any]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[fmt.Sprint(k)] = stringValue(v)
+	}
+	return out
+}
+
+func stringValue(v any) any {
+	switch v := v.(type) {
+	case map[any]any:
+		return stringMap(v)
+	case []any:
+		for i := range v {
+			v[i] = stringValue(v[i])
+		}
+	}
+	return v
+}
+
+// Validate returns the problems of the file: keys which aren't in known (other than
+// the profile keys), invalid profiles, and a default profile which doesn't exist
+func (f *File) Validate(known []string) []error {
+	var errs []error
+
+	keys := make([]string, 0, len(f.Values))
+	for key := range f.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key != KeyProfile && key != KeyProfiles && !contains(known, key) {
+			errs = append(errs, fmt.Errorf("unknown key '%s'", key))
+		}
+	}
+
+	if v, ok := f.Values[KeyProfiles]; ok {
+		if _, ok := v.(map[string]any); !ok {
+			errs = append(errs, fmt.Errorf("'%s' must map profile names to settings", KeyProfiles))
+		}
+	}
+	for _, name := range f.Profiles() {
+		if err := ValidProfileName(name); err != nil {
+			errs = append(errs, err)
+		}
+		values, _ := f.Profile(name)
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !IsProfileKey(key) {
+				errs = append(errs, fmt.Errorf("profile '%s': '%s' can't be set in a profile", name, key))
+			}
+		}
+	}
+
+	if v, ok := f.Values[KeyProfile]; ok {
+		name := fmt.Sprint(v)
+		if _, ok := f.Profile(name); !ok && name != DefaultProfile {
+			errs = append(errs, fmt.Errorf("default profile '%s' not found", name))
+		}
+	}
+	return errs
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
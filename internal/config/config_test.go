@@ -0,0 +1,109 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".urlquery-cli.yaml")
+
+	f, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Values) != 0 {
+		t.Fatalf("missing file: got %v", f.Values)
+	}
+
+	f.Set("apikey", "personal")
+	f.SetProfile("team", map[string]any{"apikey": "team-key"})
+	f.SetProfile("staging", map[string]any{"apikey": "staging-key", "apigw_base": "https://staging.example.com"})
+	f.Set(KeyProfile, "team")
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	f, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Profiles(); !reflect.DeepEqual(got, []string{"staging", "team"}) {
+		t.Errorf("Profiles() = %v", got)
+	}
+	if v, ok := f.Lookup("profiles.staging.apigw_base"); !ok || v != "https://staging.example.com" {
+		t.Errorf("Lookup = %v, %v", v, ok)
+	}
+	if _, ok := f.Lookup("apikey.nested"); ok {
+		t.Error("Lookup of a key below a string value")
+	}
+	values, ok := f.Profile("team")
+	if !ok || values["apikey"] != "team-key" {
+		t.Errorf("Profile(team) = %v, %v", values, ok)
+	}
+}
+
+func TestRemoveProfile(t *testing.T) {
+	f := &File{Values: map[string]any{}}
+	f.SetProfile("team", map[string]any{"apikey": "team-key"})
+	f.Set(KeyProfile, "team")
+
+	if f.RemoveProfile("other") {
+		t.Error("removed a missing profile")
+	}
+	if !f.RemoveProfile("team") {
+		t.Fatal("team not removed")
+	}
+	if len(f.Values) != 0 {
+		t.Errorf("values left after removing the last profile: %v", f.Values)
+	}
+}
+
+func TestValidProfileName(t *testing.T) {
+	for _, name := range []string{"team", "prod-eu", "ci_2"} {
+		if err := ValidProfileName(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "default", "Team", "a.b", "-x"} {
+		if err := ValidProfileName(name); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	f := &File{Values: map[string]any{
+		"apikey":  "abc",
+		"apikeys": "typo",
+		"profile": "missing",
+		"profiles": map[string]any{
+			"team": map[string]any{"apikey": "def", "output": "/tmp"},
+			"Bad":  map[string]any{},
+		},
+	}}
+
+	var got []string
+	for _, err := range f.Validate([]string{"apikey"}) {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"unknown key 'apikeys'",
+		"invalid profile name 'Bad': use lower case letters, digits, '-' and '_'",
+		"profile 'team': 'output' can't be set in a profile",
+		"default profile 'missing' not found",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Validate() =\n%q\nwant\n%q", got, want)
+	}
+}
@@ -0,0 +1,378 @@
+// Package diff compares two reports, typically two scans of the same URL: the domains,
+// IPs and ASNs contacted, the final URL and title, the resources loaded, the alerts
+// of each sensor and the certificates of the hosts found in both reports.
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/ioc"
+)
+
+// Diff is the difference from one report to another
+type Diff struct {
+	From Side `json:"from"`
+	To   Side `json:"to"`
+
+	FinalURL *Change `json:"final_url,omitempty"`
+	Title    *Change `json:"title,omitempty"`
+
+	Domains      Set                 `json:"domains"`
+	IPs          Set                 `json:"ips"`
+	ASNs         Set                 `json:"asns"`
+	Resources    Resources           `json:"resources"`
+	Alerts       []SensorAlerts      `json:"alerts"`
+	Certificates []CertificateChange `json:"certificates"`
+}
+
+// Side identifies a compared report
+type Side struct {
+	ReportID string `json:"report_id"`
+	Version  int    `json:"version"`
+	Date     string `json:"date"`
+	URL      string `json:"url"`
+}
+
+// Change is a changed value
+type Change struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Set lists the values only found in the first report (removed) or the second (added)
+type Set struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+func (s Set) Empty() bool {
+	return len(s.Added) == 0 && len(s.Removed) == 0
+}
+
+// Resource is a response content, by sha256
+type Resource struct {
+	Sha256   string `json:"sha256"`
+	URL      string `json:"url"`
+	MimeType string `json:"mime_type,omitempty"`
+	Size     int    `json:"size"`
+}
+
+type Resources struct {
+	Added   []Resource `json:"added"`
+	Removed []Resource `json:"removed"`
+}
+
+func (r Resources) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0
+}
+
+// SensorAlerts are the alerts of a sensor which appeared or disappeared
+type SensorAlerts struct {
+	Sensor string `json:"sensor"`
+	Set
+}
+
+// Certificate is the certificate of a host
+type Certificate struct {
+	Subject   string `json:"subject"`
+	Issuer    string `json:"issuer"`
+	NotBefore string `json:"not_before"`
+	NotAfter  string `json:"not_after"`
+	Sha256    string `json:"sha256"`
+}
+
+func (c *Certificate) String() string {
+	if c == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%s (issuer %s, valid %s to %s, sha256 %s)", c.Subject, c.Issuer, c.NotBefore, c.NotAfter, c.Sha256)
+}
+
+// CertificateChange is a host with another certificate in the second report. From or
+// To is nil when the host is only served over https in one of the reports.
+type CertificateChange struct {
+	Fqdn string       `json:"fqdn"`
+	From *Certificate `json:"from"`
+	To   *Certificate `json:"to"`
+}
+
+// Changed reports if anything differs between the reports
+func (d *Diff) Changed() bool {
+	return d.FinalURL != nil || d.Title != nil || !d.Domains.Empty() || !d.IPs.Empty() || !d.ASNs.Empty() ||
+		!d.Resources.Empty() || len(d.Alerts) > 0 || len(d.Certificates) > 0
+}
+
+// Columns and rows used by the csv and table output formats, one row per change
+
+func (d *Diff) Columns() []string {
+	return []string{"section", "change", "value"}
+}
+
+func (d *Diff) Rows() [][]string {
+	rows := [][]string{}
+	change := func(section string, c *Change) {
+		if c != nil {
+			rows = append(rows, []string{section, "changed", c.From + " -> " + c.To})
+		}
+	}
+	set := func(section string, s Set) {
+		for _, v := range s.Added {
+			rows = append(rows, []string{section, "added", v})
+		}
+		for _, v := range s.Removed {
+			rows = append(rows, []string{section, "removed", v})
+		}
+	}
+
+	change("final_url", d.FinalURL)
+	change("title", d.Title)
+	set("domains", d.Domains)
+	set("ips", d.IPs)
+	set("asns", d.ASNs)
+	for _, r := range d.Resources.Added {
+		rows = append(rows, []string{"resources", "added", r.Sha256 + " " + r.URL})
+	}
+	for _, r := range d.Resources.Removed {
+		rows = append(rows, []string{"resources", "removed", r.Sha256 + " " + r.URL})
+	}
+	for _, a := range d.Alerts {
+		set("alerts "+a.Sensor, a.Set)
+	}
+	for _, c := range d.Certificates {
+		rows = append(rows, []string{"certificates", "changed", c.Fqdn + ": " + c.From.String() + " -> " + c.To.String()})
+	}
+	return rows
+}
+
+// Compare returns the changes from report a to report b
+func Compare(a, b *api.Report) *Diff {
+	d := &Diff{
+		From:         side(a),
+		To:           side(b),
+		Domains:      compareSets(domains(a), domains(b)),
+		IPs:          compareSets(ips(a), ips(b)),
+		ASNs:         compareSets(asns(a), asns(b)),
+		Resources:    compareResources(resources(a), resources(b)),
+		Alerts:       []SensorAlerts{},
+		Certificates: []CertificateChange{},
+	}
+
+	if from, to := ioc.FullURL(a.Final.Url), ioc.FullURL(b.Final.Url); from != to {
+		d.FinalURL = &Change{From: from, To: to}
+	}
+	if a.Final.Title != b.Final.Title {
+		d.Title = &Change{From: a.Final.Title, To: b.Final.Title}
+	}
+
+	alertsA, alertsB := alerts(a), alerts(b)
+	for _, sensor := range keys(alertsA, alertsB) {
+		if s := compareSets(alertsA[sensor], alertsB[sensor]); !s.Empty() {
+			d.Alerts = append(d.Alerts, SensorAlerts{Sensor: sensor, Set: s})
+		}
+	}
+
+	hostsA, hostsB := hosts(a), hosts(b)
+	certsA, certsB := certificates(a), certificates(b)
+	for _, fqdn := range keys(certsA, certsB) {
+		if !hostsA[fqdn] || !hostsB[fqdn] {
+			continue // Added or removed domains
+		}
+		from, to := certsA[fqdn], certsB[fqdn]
+		if from == nil || to == nil || !strings.EqualFold(from.Sha256, to.Sha256) {
+			d.Certificates = append(d.Certificates, CertificateChange{Fqdn: fqdn, From: from, To: to})
+		}
+	}
+	return d
+}
+
+func side(r *api.Report) Side {
+	return Side{ReportID: r.ID, Version: r.Version, Date: r.Date, URL: ioc.FullURL(r.Url)}
+}
+
+// set is a set of values, with the label shown for each of them
+type set map[string]string
+
+func (s set) add(key, label string) {
+	if key == "" {
+		return
+	}
+	if _, ok := s[key]; !ok {
+		s[key] = label
+	}
+}
+
+func compareSets(a, b set) Set {
+	s := Set{Added: []string{}, Removed: []string{}}
+	for _, key := range keys(a, b) {
+		_, inA := a[key]
+		_, inB := b[key]
+		switch {
+		case inB && !inA:
+			s.Added = append(s.Added, b[key])
+		case inA && !inB:
+			s.Removed = append(s.Removed, a[key])
+		}
+	}
+	return s
+}
+
+// keys returns the sorted keys of the maps
+func keys[V any](maps ...map[string]V) []string {
+	seen := make(map[string]bool)
+	var list []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				list = append(list, k)
+			}
+		}
+	}
+	sort.Strings(list)
+	return list
+}
+
+// domains returns the domains of the domain summary
+func domains(r *api.Report) set {
+	s := set{}
+	for _, summary := range r.Summary {
+		fqdn := strings.ToLower(summary.Fqdn)
+		s.add(fqdn, fqdn)
+	}
+	return s
+}
+
+func ips(r *api.Report) set {
+	s := set{}
+	s.add(r.Ip.Addr, r.Ip.Addr)
+	for _, summary := range r.Summary {
+		s.add(summary.Ip.Addr, summary.Ip.Addr)
+	}
+	for _, tx := range r.HttpTransactions {
+		s.add(tx.Ip.Addr, tx.Ip.Addr)
+	}
+	return s
+}
+
+func asns(r *api.Report) set {
+	s := set{}
+	add := func(ip api.IP) {
+		if ip.ASN == 0 {
+			return
+		}
+		label := fmt.Sprintf("AS%d", ip.ASN)
+		if ip.AS != "" {
+			label += " " + ip.AS
+		}
+		s.add(fmt.Sprint(ip.ASN), label)
+	}
+
+	add(r.Ip)
+	for _, summary := range r.Summary {
+		add(summary.Ip)
+	}
+	for _, tx := range r.HttpTransactions {
+		add(tx.Ip)
+	}
+	return s
+}
+
+func resources(r *api.Report) map[string]Resource {
+	m := make(map[string]Resource)
+	for _, tx := range r.HttpTransactions {
+		c := tx.Response.Content
+		hash := strings.ToLower(c.Sha256)
+		if _, ok := m[hash]; hash == "" || ok {
+			continue
+		}
+		m[hash] = Resource{Sha256: hash, URL: ioc.FullURL(tx.Url), MimeType: c.MimeType, Size: c.Size}
+	}
+	return m
+}
+
+func compareResources(a, b map[string]Resource) Resources {
+	r := Resources{Added: []Resource{}, Removed: []Resource{}}
+	for _, hash := range keys(a, b) {
+		resA, inA := a[hash]
+		resB, inB := b[hash]
+		switch {
+		case inB && !inA:
+			r.Added = append(r.Added, resB)
+		case inA && !inB:
+			r.Removed = append(r.Removed, resA)
+		}
+	}
+	return r
+}
+
+// alerts returns the alerts by sensor name
+func alerts(r *api.Report) map[string]set {
+	m := make(map[string]set)
+	add := func(sensor, alert string) {
+		if alert == "" {
+			return
+		}
+		if m[sensor] == nil {
+			m[sensor] = set{}
+		}
+		m[sensor].add(alert, alert)
+	}
+
+	for _, s := range r.Sensors.NetworkSensors {
+		for _, a := range s.Alerts {
+			add(s.SensorName, a.Alert)
+		}
+	}
+	for _, s := range r.Sensors.AnalyzerSensors {
+		for _, a := range s.Alerts {
+			add(s.SensorName, a.Alert)
+		}
+	}
+	for _, a := range r.Sensors.UrlQueryAlerts {
+		add(a.SensorName, a.Alert)
+	}
+	for _, tx := range r.HttpTransactions {
+		for _, a := range tx.Alerts.IDSAlerts {
+			add(a.SensorName, a.Alert)
+		}
+		for _, a := range tx.Alerts.AnalyzerAlerts {
+			add(a.SensorName, a.Alert)
+		}
+		for _, a := range tx.Alerts.UrlqueryAlerts {
+			add(a.SensorName, a.Alert)
+		}
+	}
+	return m
+}
+
+// hosts returns the hosts requested in the report
+func hosts(r *api.Report) map[string]bool {
+	m := make(map[string]bool)
+	for _, tx := range r.HttpTransactions {
+		m[strings.ToLower(tx.Url.Fqdn)] = true
+	}
+	return m
+}
+
+// certificates returns the first certificate seen for each host
+func certificates(r *api.Report) map[string]*Certificate {
+	m := make(map[string]*Certificate)
+	for _, tx := range r.HttpTransactions {
+		fqdn := strings.ToLower(tx.Url.Fqdn)
+		if _, ok := m[fqdn]; ok || tx.SecurityInfo == nil || fqdn == "" {
+			continue
+		}
+		c := tx.SecurityInfo.Cert
+		m[fqdn] = &Certificate{
+			Subject:   c.Subject.CommonName,
+			Issuer:    c.Issuer.CommonName,
+			NotBefore: c.Validity.Start,
+			NotAfter:  c.Validity.End,
+			Sha256:    strings.ToLower(c.Fingerprint.Sha256),
+		}
+	}
+	return m
+}
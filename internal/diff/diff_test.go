@@ -0,0 +1,104 @@
+package diff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/output"
+)
+
+func transaction(fqdn, ip string, asn int, sha256, cert string) api.HttpTransaction {
+	tx := api.HttpTransaction{
+		Url: api.URL{Schema: "https", Addr: fqdn + "/", Fqdn: fqdn},
+		Ip:  api.IP{Addr: ip, ASN: asn},
+	}
+	tx.Response.Content.Sha256 = sha256
+	if cert != "" {
+		tx.SecurityInfo = &api.HttpSecurityInfo{}
+		tx.SecurityInfo.Cert.Subject.CommonName = fqdn
+		tx.SecurityInfo.Cert.Fingerprint.Sha256 = cert
+	}
+	return tx
+}
+
+func TestCompare(t *testing.T) {
+	a := &api.Report{}
+	a.ID = "a"
+	a.Final.Title = "Sign in"
+	a.Summary = []api.ReportSummary{{Fqdn: "example.com"}, {Fqdn: "cdn.example.net"}}
+	a.HttpTransactions = []api.HttpTransaction{
+		transaction("example.com", "192.0.2.1", 64500, "AAA", "c1"),
+		transaction("cdn.example.net", "192.0.2.2", 64500, "BBB", ""),
+	}
+	a.Sensors.UrlQueryAlerts = []api.UrlqueryAlert{{SensorName: "urlquery", Alert: "Phishing kit"}}
+
+	b := &api.Report{}
+	b.ID = "b"
+	b.Final.Title = "Sign in"
+	b.Summary = []api.ReportSummary{{Fqdn: "example.com"}, {Fqdn: "evil.example.org"}}
+	b.HttpTransactions = []api.HttpTransaction{
+		transaction("example.com", "192.0.2.1", 64500, "aaa", "c2"),
+		transaction("evil.example.org", "198.51.100.7", 64501, "CCC", "c3"),
+	}
+	b.Sensors.NetworkSensors = []api.IDSSensor{{SensorName: "suricata", Alerts: []api.IDSAlert{{Alert: "ET MALWARE Beacon"}}}}
+
+	d := Compare(a, b)
+	if !d.Changed() || d.Title != nil || d.FinalURL != nil {
+		t.Fatalf("Unexpected diff %+v", d)
+	}
+
+	if len(d.Domains.Added) != 1 || d.Domains.Added[0] != "evil.example.org" || d.Domains.Removed[0] != "cdn.example.net" {
+		t.Errorf("Unexpected domains %+v", d.Domains)
+	}
+	if len(d.IPs.Added) != 1 || d.IPs.Added[0] != "198.51.100.7" || d.IPs.Removed[0] != "192.0.2.2" {
+		t.Errorf("Unexpected IPs %+v", d.IPs)
+	}
+	if len(d.ASNs.Added) != 1 || d.ASNs.Added[0] != "AS64501" || len(d.ASNs.Removed) != 0 {
+		t.Errorf("Unexpected ASNs %+v", d.ASNs)
+	}
+
+	// Hashes are compared without case
+	if len(d.Resources.Added) != 1 || d.Resources.Added[0].Sha256 != "ccc" || d.Resources.Removed[0].Sha256 != "bbb" {
+		t.Errorf("Unexpected resources %+v", d.Resources)
+	}
+
+	if len(d.Alerts) != 2 || d.Alerts[0].Sensor != "suricata" || d.Alerts[1].Removed[0] != "Phishing kit" {
+		t.Errorf("Unexpected alerts %+v", d.Alerts)
+	}
+
+	// Only hosts of both reports
+	if len(d.Certificates) != 1 || d.Certificates[0].Fqdn != "example.com" || d.Certificates[0].To.Sha256 != "c2" {
+		t.Errorf("Unexpected certificates %+v", d.Certificates)
+	}
+
+	if Compare(a, a).Changed() {
+		t.Errorf("Expected no changes comparing a report to itself")
+	}
+}
+
+func TestDiffTabular(t *testing.T) {
+	d := &Diff{
+		FinalURL: &Change{From: "https://example.com/", To: "https://example.com/login"},
+		Domains:  Set{Added: []string{"evil.example.org"}, Removed: []string{"cdn.example.net"}},
+		Alerts:   []SensorAlerts{{Sensor: "suricata", Set: Set{Added: []string{"ET MALWARE Beacon"}}}},
+	}
+
+	f, err := output.New("csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Format(&buf, d); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "section,change,value\n" +
+		"final_url,changed,https://example.com/ -> https://example.com/login\n" +
+		"domains,added,evil.example.org\n" +
+		"domains,removed,cdn.example.net\n" +
+		"alerts suricata,added,ET MALWARE Beacon\n"
+	if buf.String() != want {
+		t.Errorf("csv = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,300 @@
+// Package doctor diagnoses the setup of urlquery-cli: the config file, the output
+// directory, the proxy, and the connection to the API gateway (DNS, TLS, clock and
+// API key). Each check returns a result with a status, to print as a checklist or as
+// JSON for support tickets.
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+// Status of a check
+type Status string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+	StatusSkip Status = "skip"
+)
+
+// Check is the result of a diagnostic
+type Check struct {
+	Name   string `json:"name"`
+	Status Status `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+func pass(name, format string, args ...any) Check {
+	return Check{Name: name, Status: StatusPass, Detail: fmt.Sprintf(format, args...)}
+}
+
+func warn(name, format string, args ...any) Check {
+	return Check{Name: name, Status: StatusWarn, Detail: fmt.Sprintf(format, args...)}
+}
+
+func fail(name, format string, args ...any) Check {
+	return Check{Name: name, Status: StatusFail, Detail: fmt.Sprintf(format, args...)}
+}
+
+func skip(name, format string, args ...any) Check {
+	return Check{Name: name, Status: StatusSkip, Detail: fmt.Sprintf(format, args...)}
+}
+
+// Setting is a resolved config value, and where it comes from (flag, env, profile,
+// file or default)
+type Setting struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Source string `json:"source"`
+}
+
+// Report gathers the checks, for support tickets
+type Report struct {
+	Time     time.Time `json:"time"`
+	Version  string    `json:"version"`
+	OS       string    `json:"os"`
+	Checks   []Check   `json:"checks"`
+	Settings []Setting `json:"settings"`
+}
+
+// NewReport returns an empty report for a CLI version
+func NewReport(version string) *Report {
+	return &Report{
+		Time:    time.Now().UTC(),
+		Version: version,
+		OS:      runtime.GOOS + "/" + runtime.GOARCH,
+	}
+}
+
+// Add appends checks to the report
+func (r *Report) Add(checks ...Check) {
+	r.Checks = append(r.Checks, checks...)
+}
+
+// OK reports if no check failed
+func (r *Report) OK() bool {
+	for _, c := range r.Checks {
+		if c.Status == StatusFail {
+			return false
+		}
+	}
+	return true
+}
+
+// Permissions checks that a file holding secrets is only readable by its owner
+func Permissions(path string) Check {
+	const name = "config permissions"
+	if runtime.GOOS == "windows" {
+		return skip(name, "not checked on Windows")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return skip(name, "no config file")
+	}
+	if perm := info.Mode().Perm(); perm&0077 != 0 {
+		return warn(name, "readable by other users (%#o), run: chmod 600 %s", perm, path)
+	}
+	return pass(name, "%#o", info.Mode().Perm())
+}
+
+// OutputDir checks that downloads can be written to the directory
+func OutputDir(dir string) Check {
+	const name = "output directory"
+	info, err := os.Stat(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return warn(name, "%s doesn't exist yet, it is created on download", dir)
+	} else if err != nil {
+		return fail(name, "%v", err)
+	}
+	if !info.IsDir() {
+		return fail(name, "%s is not a directory", dir)
+	}
+
+	f, err := os.CreateTemp(dir, ".urlquery-doctor-*")
+	if err != nil {
+		return fail(name, "%s is not writable: %v", dir, err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+	return pass(name, "%s is writable", dir)
+}
+
+// proxyEnv are the environment variables used by Go for proxies
+var proxyEnv = []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy", "NO_PROXY", "no_proxy"}
+
+// Proxy reports the proxy environment variables, and the proxy used for the target
+// URL by the proxy function (e.g. http.ProxyFromEnvironment)
+func Proxy(target string, proxy func(*http.Request) (*url.URL, error)) Check {
+	const name = "proxy"
+
+	var vars []string
+	for _, env := range proxyEnv {
+		if v, ok := os.LookupEnv(env); ok {
+			vars = append(vars, env+"="+redactURL(v))
+		}
+	}
+	env := ""
+	if len(vars) > 0 {
+		env = " (" + strings.Join(vars, " ") + ")"
+	}
+
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return fail(name, "invalid API gateway URL: %v", err)
+	}
+	u, err := proxy(req)
+	if err != nil {
+		return fail(name, "invalid proxy%s: %v", env, err)
+	}
+	if u == nil {
+		if len(vars) == 0 {
+			return pass(name, "no proxy")
+		}
+		return pass(name, "not used for %s%s", req.URL.Host, env)
+	}
+	return pass(name, "%s used for %s%s", redactURL(u.String()), req.URL.Host, env)
+}
+
+// redactURL removes the password of a proxy URL
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	return u.Redacted()
+}
+
+// DNS checks that the host of the API gateway resolves
+func DNS(ctx context.Context, host string) Check {
+	const name = "dns"
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return fail(name, "%v", err)
+	}
+	return pass(name, "%s: %s", host, strings.Join(addrs, ", "))
+}
+
+// Maximum difference with the clock of the API gateway
+const (
+	clockWarn = 30 * time.Second
+	clockFail = 5 * time.Minute
+)
+
+// Endpoint connects to the API gateway, and returns the connection, TLS and clock
+// checks. Any HTTP response means the gateway is reachable.
+func Endpoint(ctx context.Context, client *http.Client, base string) []Check {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base, nil)
+	if err != nil {
+		return []Check{fail("connection", "invalid API gateway URL: %v", err)}
+	}
+	req.Header.Set("User-Agent", "urlquery-cli doctor")
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		checks := []Check{fail("connection", "%v", err)}
+		var certErr *tls.CertificateVerificationError
+		var unknownAuth x509.UnknownAuthorityError
+		var hostErr x509.HostnameError
+		if errors.As(err, &certErr) || errors.As(err, &unknownAuth) || errors.As(err, &hostErr) {
+			checks = append(checks, fail("tls", "certificate not trusted: %v", err))
+		} else {
+			checks = append(checks, skip("tls", "no connection"))
+		}
+		return append(checks, skip("clock", "no connection"))
+	}
+	resp.Body.Close()
+	elapsed := time.Since(start)
+
+	checks := []Check{pass("connection", "%s answered %s in %s", base, resp.Status, elapsed.Round(time.Millisecond))}
+	checks = append(checks, tlsCheck(resp))
+	return append(checks, clockCheck(resp, start.Add(elapsed/2)))
+}
+
+func tlsCheck(resp *http.Response) Check {
+	const name = "tls"
+	if resp.TLS == nil {
+		return warn(name, "plain HTTP, the API key is sent unencrypted")
+	}
+	if len(resp.TLS.PeerCertificates) == 0 {
+		return pass(name, "%s", tls.VersionName(resp.TLS.Version))
+	}
+
+	cert := resp.TLS.PeerCertificates[0]
+	detail := fmt.Sprintf("%s, certificate for %s issued by %s, valid until %s",
+		tls.VersionName(resp.TLS.Version), cert.Subject.CommonName, cert.Issuer.CommonName, cert.NotAfter.Format(time.DateOnly))
+	if time.Until(cert.NotAfter) < 14*24*time.Hour {
+		return warn(name, "%s (expires soon)", detail)
+	}
+	return pass(name, "%s", detail)
+}
+
+// clockCheck compares the Date header of the response with the local time of the request
+func clockCheck(resp *http.Response, local time.Time) Check {
+	const name = "clock"
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return skip(name, "no Date header in the response")
+	}
+
+	// The Date header has a one second precision
+	skew := local.Sub(date).Truncate(time.Second)
+	if skew.Abs() <= time.Second {
+		return pass(name, "in sync with the API gateway")
+	}
+	detail := fmt.Sprintf("local clock is %s ahead of the API gateway", skew)
+	if skew < 0 {
+		detail = fmt.Sprintf("local clock is %s behind the API gateway", -skew)
+	}
+	switch {
+	case skew.Abs() > clockFail:
+		return fail(name, "%s", detail)
+	case skew.Abs() > clockWarn:
+		return warn(name, "%s", detail)
+	}
+	return pass(name, "%s", detail)
+}
+
+// APIKey checks that the API key is accepted, with a cheap authenticated call
+func APIKey(ctx context.Context, apikey string, call func(ctx context.Context) error) Check {
+	const name = "api key"
+	if apikey == "" {
+		return fail(name, "not set, use 'config set apikey <value>' or --apikey")
+	}
+
+	err := call(ctx)
+	var apiErr *api.UrlqueryApiError
+	switch {
+	case err == nil:
+		return pass(name, "accepted")
+	case errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden):
+		return fail(name, "rejected: %v", err)
+	}
+	return warn(name, "not verified: %v", err)
+}
+
+func (r *Report) Columns() []string {
+	return []string{"check", "status", "detail"}
+}
+
+func (r *Report) Rows() [][]string {
+	rows := make([][]string, 0, len(r.Checks))
+	for _, c := range r.Checks {
+		rows = append(rows, []string{c.Name, string(c.Status), c.Detail})
+	}
+	return rows
+}
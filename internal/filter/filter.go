@@ -0,0 +1,291 @@
+// Package filter selects fields from and filters command results, using dotted
+// JSON paths (e.g. "url.fqdn" or "stats.alert_count.urlquery") evaluated against
+// the JSON representation of the API types.
+//
+// Filter expressions compare paths with literals, and combine them with boolean operators:
+//
+//	stats.alert_count.urlquery > 0 && tags contains "phishing"
+//	!(url.fqdn == "example.com") || ip.country_code ~= "^(RU|CN)$"
+//
+// Supported operators are == != > >= < <= contains ~= (regular expression), && || ! and parentheses.
+// A path without an operator is true when it has a non-empty value. When a path goes
+// through a list (e.g. "summary.fqdn") the comparison is true if any of the values matches,
+// and != is true if none of them is equal, or the path is missing. contains matches
+// substrings of strings and elements of lists, ignoring case.
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Filter is a compiled filter expression
+type Filter struct {
+	expr string
+	root node
+}
+
+// Parse compiles a filter expression
+func Parse(expr string) (*Filter, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", p.peek().text, p.peek().pos)
+	}
+
+	return &Filter{expr: expr, root: root}, nil
+}
+
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Match reports if v matches the filter. v is evaluated using its JSON representation.
+func (f *Filter) Match(v any) (bool, error) {
+	doc, err := Normalize(v)
+	if err != nil {
+		return false, err
+	}
+	return f.root.eval(doc), nil
+}
+
+// Normalize converts v to its generic JSON representation (maps, slices, strings,
+// json.Number, bools and nil).
+func Normalize(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+
+	var doc any
+	err = dec.Decode(&doc)
+	return doc, err
+}
+
+// Lookup returns the values found at a dotted path. Lists met along the path are
+// expanded, so the result can hold several values. Numeric path elements index lists.
+func Lookup(doc any, path string) []any {
+	values := []any{doc}
+	if path == "" || path == "." {
+		return values
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		var next []any
+		for _, v := range values {
+			next = append(next, lookupKey(v, key)...)
+		}
+		values = next
+	}
+	return values
+}
+
+func lookupKey(v any, key string) []any {
+	switch t := v.(type) {
+	case map[string]any:
+		if value, ok := t[key]; ok {
+			return []any{value}
+		}
+
+	case []any:
+		if i, err := strconv.Atoi(key); err == nil {
+			if i >= 0 && i < len(t) {
+				return []any{t[i]}
+			}
+			return nil
+		}
+
+		var values []any
+		for _, item := range t {
+			values = append(values, lookupKey(item, key)...)
+		}
+		return values
+	}
+
+	return nil
+}
+
+// --- evaluation ---
+
+type node interface {
+	eval(doc any) bool
+}
+
+type andNode struct{ left, right node }
+type orNode struct{ left, right node }
+type notNode struct{ expr node }
+
+func (n andNode) eval(doc any) bool { return n.left.eval(doc) && n.right.eval(doc) }
+func (n orNode) eval(doc any) bool  { return n.left.eval(doc) || n.right.eval(doc) }
+func (n notNode) eval(doc any) bool { return !n.expr.eval(doc) }
+
+// existsNode is a path without an operator
+type existsNode struct{ path string }
+
+func (n existsNode) eval(doc any) bool {
+	for _, v := range Lookup(doc, n.path) {
+		if truthy(v) {
+			return true
+		}
+	}
+	return false
+}
+
+type compareNode struct {
+	path  string
+	op    string
+	value any // string, float64, bool or nil
+	re    *regexp.Regexp
+}
+
+func (n compareNode) eval(doc any) bool {
+	// != is the negation of ==, also for lists and missing paths
+	if n.op == "!=" {
+		eq := n
+		eq.op = "=="
+		return !eq.eval(doc)
+	}
+
+	for _, v := range Lookup(doc, n.path) {
+		if n.match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n compareNode) match(v any) bool {
+	// Lists match if any of their elements match, and contain the literal
+	if list, ok := v.([]any); ok {
+		if n.op == "contains" {
+			for _, item := range list {
+				if s, ok := item.(string); ok {
+					if strings.EqualFold(s, toString(n.value)) {
+						return true
+					}
+				} else if equal(item, n.value) {
+					return true
+				}
+			}
+			return false
+		}
+		for _, item := range list {
+			if n.match(item) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch n.op {
+	case "==":
+		return equal(v, n.value)
+	case "contains":
+		s, ok := v.(string)
+		return ok && strings.Contains(strings.ToLower(s), strings.ToLower(toString(n.value)))
+	case "~=":
+		return v != nil && n.re.MatchString(toString(v))
+	}
+
+	// Ordering comparisons, numeric when both sides are numbers
+	a, aok := toNumber(v)
+	b, bok := toNumber(n.value)
+	if aok && bok {
+		return compare(a, b, n.op)
+	}
+	if s, ok := v.(string); ok {
+		if lit, ok := n.value.(string); ok {
+			return compare(float64(strings.Compare(s, lit)), 0, n.op)
+		}
+	}
+	return false
+}
+
+func compare(a, b float64, op string) bool {
+	switch op {
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	}
+	return false
+}
+
+func equal(v any, literal any) bool {
+	if a, ok := toNumber(v); ok {
+		if b, ok := toNumber(literal); ok {
+			return a == b
+		}
+	}
+	switch t := v.(type) {
+	case string:
+		s, ok := literal.(string)
+		return ok && t == s
+	case bool:
+		b, ok := literal.(bool)
+		return ok && t == b
+	case nil:
+		return literal == nil
+	}
+	return false
+}
+
+func truthy(v any) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	case string:
+		return t != ""
+	case json.Number:
+		f, _ := t.Float64()
+		return f != 0
+	case []any:
+		return len(t) > 0
+	case map[string]any:
+		return len(t) > 0
+	}
+	return true
+}
+
+func toNumber(v any) (float64, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	case float64:
+		return t, true
+	}
+	return 0, false
+}
+
+func toString(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	}
+	return fmt.Sprint(v)
+}
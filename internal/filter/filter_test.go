@@ -0,0 +1,110 @@
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+func testReport() *api.ReportOverview {
+	var r api.ReportOverview
+	r.ID = "82c4121d-d037-4d60-9f74-517bf00091ce"
+	r.Tags = []string{"phishing", "microsoft"}
+	r.Url.Fqdn = "login.example.com"
+	r.Ip.CountryCode = "NL"
+	r.Stats.AlertCount.Urlquery = 3
+	r.Summary = []api.ReportSummary{
+		{Fqdn: "login.example.com", AlertCount: 3},
+		{Fqdn: "cdn.example.net"},
+	}
+	return &r
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`stats.alert_count.urlquery > 0`, true},
+		{`stats.alert_count.urlquery >= 3`, true},
+		{`stats.alert_count.urlquery < 3`, false},
+		{`stats.alert_count.ids == 0`, true},
+		{`tags contains "phishing"`, true},
+		{`tags contains "phish"`, false},
+		{`url.fqdn contains "EXAMPLE"`, true},
+		{`stats.alert_count.urlquery>0 && tags contains "phishing"`, true},
+		{`stats.alert_count.urlquery>0 && tags contains "malware"`, false},
+		{`tags contains "malware" || ip.country_code == "NL"`, true},
+		{`!(ip.country_code == "NL")`, false},
+		{`not ip.country_code == NL`, false},
+		{`ip.country_code != "NL"`, false},
+		{`ip.country_code != "SE"`, true},
+		{`tags != "phishing"`, false},
+		{`tags != "malware"`, true},
+		{`summary.fqdn != "cdn.example.net"`, false},
+		{`missing.field != "x"`, true},
+		{`tags contains "PHISHING"`, true},
+		{`summary.fqdn contains "CDN.example.NET"`, true},
+		{`url.fqdn ~= "^login\\."`, true},
+		{`summary.fqdn == "cdn.example.net"`, true},
+		{`summary.alert_count > 5`, false},
+		{`summary.0.fqdn == "login.example.com"`, true},
+		{`final.title`, false},
+		{`tags`, true},
+		{`missing.field == "x"`, false},
+		{`(tags contains "a" || tags contains "microsoft") and stats.alert_count.urlquery == 3`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := Parse(tt.expr)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			got, err := f.Match(testReport())
+			if err != nil {
+				t.Fatalf("Match() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`tags contains`,
+		`(tags`,
+		`tags == "x" &&`,
+		`tags == "unterminated`,
+		`url.fqdn ~= "("`,
+		`== 1`,
+		`tags == 1 2`,
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) expected error", expr)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	rec, err := Select(testReport(), []string{"report_id", "url.fqdn", "stats.alert_count.urlquery", "summary.fqdn", "missing"})
+	if err != nil {
+		t.Fatalf("Select() error = %v", err)
+	}
+
+	data, _ := json.Marshal(rec)
+	want := `{"report_id":"82c4121d-d037-4d60-9f74-517bf00091ce","url.fqdn":"login.example.com","stats.alert_count.urlquery":3,"summary.fqdn":["login.example.com","cdn.example.net"],"missing":null}`
+	if string(data) != want {
+		t.Errorf("Select() = %s, want %s", data, want)
+	}
+
+	row := rec.Rows()[0]
+	if row[2] != "3" || row[3] != "login.example.com cdn.example.net" || row[4] != "" {
+		t.Errorf("Rows() = %q", row)
+	}
+}
@@ -0,0 +1,266 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokPath tokenKind = iota
+	tokString
+	tokNumber
+	tokOp
+	tokAnd
+	tokOr
+	tokNot
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+var operators = []string{"==", "!=", ">=", "<=", "~=", ">", "<"}
+
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	runes := []rune(expr)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		rest := string(runes[i:])
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+
+		case strings.HasPrefix(rest, "&&"):
+			tokens = append(tokens, token{tokAnd, "&&", i})
+			i += 2
+
+		case strings.HasPrefix(rest, "||"):
+			tokens = append(tokens, token{tokOr, "||", i})
+			i += 2
+
+		case r == '"' || r == '\'':
+			start := i
+			var sb strings.Builder
+			i++
+			for ; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+				}
+				sb.WriteRune(runes[i])
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, token{tokString, sb.String(), start})
+
+		default:
+			if op := matchOperator(rest); op != "" {
+				tokens = append(tokens, token{tokOp, op, i})
+				i += len(op)
+				continue
+			}
+			if r == '!' {
+				tokens = append(tokens, token{tokNot, "!", i})
+				i++
+				continue
+			}
+
+			// Words: paths, numbers, keywords
+			start := i
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("unexpected '%c' at position %d", r, i)
+			}
+			word := string(runes[start:i])
+
+			switch {
+			case word == "contains":
+				tokens = append(tokens, token{tokOp, word, start})
+			case word == "and":
+				tokens = append(tokens, token{tokAnd, word, start})
+			case word == "or":
+				tokens = append(tokens, token{tokOr, word, start})
+			case word == "not":
+				tokens = append(tokens, token{tokNot, word, start})
+			case isNumber(word):
+				tokens = append(tokens, token{tokNumber, word, start})
+			default:
+				tokens = append(tokens, token{tokPath, word, start})
+			}
+		}
+	}
+
+	return tokens, nil
+}
+
+func matchOperator(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' || r == ':'
+}
+
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
+
+// parser is a recursive descent parser for:
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | primary
+//	primary = "(" or ")" | path [ op literal ]
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *parser) peek() token {
+	if p.done() {
+		return token{pos: -1}
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *parser) accept(kind tokenKind) bool {
+	if !p.done() && p.tokens[p.pos].kind == kind {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokOr) {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokAnd) {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.accept(tokNot) {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{expr}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.done() {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	if p.accept(tokLParen) {
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(tokRParen) {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		return expr, nil
+	}
+
+	path := p.peek()
+	if !p.accept(tokPath) {
+		return nil, fmt.Errorf("expected a field at position %d, got '%s'", path.pos, path.text)
+	}
+
+	op := p.peek()
+	if !p.accept(tokOp) {
+		return existsNode{path: path.text}, nil
+	}
+
+	lit := p.peek()
+	n := compareNode{path: path.text, op: op.text}
+	switch {
+	case p.accept(tokString):
+		n.value = lit.text
+	case p.accept(tokNumber):
+		n.value, _ = strconv.ParseFloat(lit.text, 64)
+	case p.accept(tokPath):
+		// Bare words: true, false, null or an unquoted string
+		switch lit.text {
+		case "true":
+			n.value = true
+		case "false":
+			n.value = false
+		case "null":
+			n.value = nil
+		default:
+			n.value = lit.text
+		}
+	default:
+		return nil, fmt.Errorf("expected a value after '%s' at position %d", op.text, op.pos)
+	}
+
+	if n.op == "~=" {
+		re, err := regexp.Compile(toString(n.value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression: %w", err)
+		}
+		n.re = re
+	}
+
+	return n, nil
+}
@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Record holds selected fields, in the order they were requested
+type Record struct {
+	Fields []string
+	Values []any
+}
+
+// Select picks the values at the given dotted paths from v. Paths matching
+// several values (through lists) hold a list, missing paths hold null.
+func Select(v any, fields []string) (*Record, error) {
+	doc, err := Normalize(v)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &Record{Fields: fields, Values: make([]any, len(fields))}
+	for i, field := range fields {
+		switch values := Lookup(doc, field); len(values) {
+		case 0:
+			rec.Values[i] = nil
+		case 1:
+			rec.Values[i] = values[0]
+		default:
+			rec.Values[i] = values
+		}
+	}
+	return rec, nil
+}
+
+// MarshalJSON writes the record as an object with the fields in order
+func (r *Record) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range r.Fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(field)
+		value, err := json.Marshal(r.Values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (r *Record) Columns() []string {
+	return r.Fields
+}
+
+func (r *Record) Rows() [][]string {
+	row := make([]string, len(r.Values))
+	for i, v := range r.Values {
+		row[i] = cellString(v)
+	}
+	return [][]string{row}
+}
+
+// cellString formats a value for csv and table output. Lists of scalars are
+// joined with spaces, other structures are written as JSON.
+func cellString(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case []any:
+		parts := make([]string, len(t))
+		for i, item := range t {
+			switch item.(type) {
+			case map[string]any, []any:
+				data, _ := json.Marshal(t)
+				return string(data)
+			}
+			parts[i] = cellString(item)
+		}
+		return strings.Join(parts, " ")
+	case map[string]any:
+		data, _ := json.Marshal(t)
+		return string(data)
+	}
+	return toString(v)
+}
@@ -0,0 +1,321 @@
+// Package har converts the HTTP transactions of urlquery reports to HAR 1.2 archives.
+//
+// See http://www.softwareishard.com/blog/har-12-spec/ for the specification.
+package har
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+const Version = "1.2"
+
+type HAR struct {
+	Log Log `json:"log"`
+}
+
+type Log struct {
+	Version string   `json:"version"`
+	Creator Creator  `json:"creator"`
+	Browser *Creator `json:"browser,omitempty"`
+	Pages   []Page   `json:"pages"`
+	Entries []Entry  `json:"entries"`
+	Comment string   `json:"comment,omitempty"`
+}
+
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type Page struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	ID              string      `json:"id"`
+	Title           string      `json:"title"`
+	PageTimings     PageTimings `json:"pageTimings"`
+}
+
+type PageTimings struct {
+	OnContentLoad int `json:"onContentLoad"`
+	OnLoad        int `json:"onLoad"`
+}
+
+type Entry struct {
+	Pageref         string   `json:"pageref,omitempty"`
+	StartedDateTime string   `json:"startedDateTime"`
+	Time            int      `json:"time"`
+	Request         Request  `json:"request"`
+	Response        Response `json:"response"`
+	Cache           struct{} `json:"cache"`
+	Timings         Timings  `json:"timings"`
+	ServerIPAddress string   `json:"serverIPAddress,omitempty"`
+
+	// Custom fields, prefixed with _ as required by the specification
+	ResourceType  string `json:"_resourceType,omitempty"`
+	SecurityState string `json:"_securityState,omitempty"`
+	Initiator     string `json:"_initiator,omitempty"`
+}
+
+type Request struct {
+	Method      string      `json:"method"`
+	URL         string      `json:"url"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	QueryString []NameValue `json:"queryString"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+type Response struct {
+	Status      int         `json:"status"`
+	StatusText  string      `json:"statusText"`
+	HTTPVersion string      `json:"httpVersion"`
+	Cookies     []Cookie    `json:"cookies"`
+	Headers     []NameValue `json:"headers"`
+	Content     Content     `json:"content"`
+	RedirectURL string      `json:"redirectURL"`
+	HeadersSize int         `json:"headersSize"`
+	BodySize    int         `json:"bodySize"`
+}
+
+type Cookie struct {
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+	Path     string `json:"path,omitempty"`
+	Domain   string `json:"domain,omitempty"`
+	Expires  string `json:"expires,omitempty"`
+	HTTPOnly bool   `json:"httpOnly,omitempty"`
+	Secure   bool   `json:"secure,omitempty"`
+}
+
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type Content struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// Timings in milliseconds. Blocked, DNS, Connect and SSL are -1 when not applicable.
+type Timings struct {
+	Blocked int `json:"blocked"`
+	DNS     int `json:"dns"`
+	Connect int `json:"connect"`
+	Send    int `json:"send"`
+	Wait    int `json:"wait"`
+	Receive int `json:"receive"`
+	SSL     int `json:"ssl"`
+}
+
+// FromReport converts the HTTP transactions of a report to a HAR archive, with the
+// report as a single page. creator is the version of the tool creating the archive.
+func FromReport(r *api.Report, creator string) *HAR {
+	pageID := "page_" + r.ID
+
+	h := &HAR{Log: Log{
+		Version: Version,
+		Creator: Creator{Name: "urlquery-cli", Version: creator},
+		Pages: []Page{{
+			StartedDateTime: r.Date,
+			ID:              pageID,
+			Title:           r.Final.Title,
+			PageTimings:     PageTimings{OnContentLoad: -1, OnLoad: -1},
+		}},
+		Entries: []Entry{},
+		Comment: "urlquery.net report " + r.ID,
+	}}
+
+	if ua := r.ReportSettings.UserAgent; ua != "" {
+		h.Log.Browser = &Creator{Name: ua, Version: ""}
+	}
+
+	for _, tx := range r.HttpTransactions {
+		h.Log.Entries = append(h.Log.Entries, entry(tx, pageID))
+	}
+
+	if h.Log.Pages[0].StartedDateTime == "" && len(h.Log.Entries) > 0 {
+		h.Log.Pages[0].StartedDateTime = h.Log.Entries[0].StartedDateTime
+	}
+	return h
+}
+
+func entry(tx api.HttpTransaction, pageID string) Entry {
+	rawURL := tx.Url.Addr
+	if tx.Url.Schema != "" && !strings.Contains(rawURL, "://") {
+		rawURL = tx.Url.Schema + "://" + rawURL
+	}
+
+	e := Entry{
+		Pageref:         pageID,
+		StartedDateTime: startedDateTime(tx),
+		ServerIPAddress: tx.Ip.Addr,
+		ResourceType:    tx.ResourceType,
+		SecurityState:   tx.SecurityState,
+		Initiator:       tx.RequestedBy,
+		Timings:         timings(tx.Timings),
+	}
+
+	e.Request = Request{
+		Method:      tx.Request.Method,
+		URL:         rawURL,
+		HTTPVersion: tx.HttpVersion,
+		Cookies:     cookies(tx.Request.Cookies),
+		Headers:     headers(tx.Request.Headers),
+		QueryString: queryString(rawURL),
+		HeadersSize: -1,
+		BodySize:    -1,
+	}
+
+	status, _ := strconv.Atoi(tx.Response.StatusCode)
+	content := tx.Response.Content
+	e.Response = Response{
+		Status:      status,
+		StatusText:  tx.Response.StatusText,
+		HTTPVersion: tx.HttpVersion,
+		Cookies:     responseCookies(tx.Response),
+		Headers:     headers(tx.Response.Headers),
+		Content: Content{
+			Size:     content.Size,
+			MimeType: content.MimeType,
+		},
+		RedirectURL: header(tx.Response.Headers, "Location"),
+		HeadersSize: -1,
+		BodySize:    content.Size,
+	}
+	if len(content.Data) > 0 {
+		e.Response.Content.Text = base64.StdEncoding.EncodeToString(content.Data)
+		e.Response.Content.Encoding = "base64"
+	}
+
+	// Total time is the sum of the applicable timings (SSL is included in connect)
+	t := e.Timings
+	e.Time = max(t.Blocked, 0) + max(t.DNS, 0) + max(t.Connect, 0) + t.Send + t.Wait + t.Receive
+	if e.Time == 0 {
+		e.Time = tx.TotalTimeUsed
+	}
+
+	return e
+}
+
+func startedDateTime(tx api.HttpTransaction) string {
+	if tx.Date != "" {
+		return tx.Date
+	}
+	if tx.Timestamp > 1e12 { // Milliseconds
+		return time.UnixMilli(tx.Timestamp).UTC().Format(time.RFC3339Nano)
+	}
+	if tx.Timestamp > 0 {
+		return time.Unix(tx.Timestamp, 0).UTC().Format(time.RFC3339Nano)
+	}
+	return ""
+}
+
+// timings converts the report timings, where send, wait and receive must not be negative
+func timings(t api.HttpTimings) Timings {
+	optional := func(v int) int {
+		if v < 0 {
+			return -1
+		}
+		return v
+	}
+	return Timings{
+		Blocked: optional(t.Blocked),
+		DNS:     optional(t.DNS),
+		Connect: optional(t.Connect),
+		Send:    max(t.Send, 0),
+		Wait:    max(t.Wait, 0),
+		Receive: max(t.Receive, 0),
+		SSL:     optional(t.SSL),
+	}
+}
+
+func headers(list []api.HttpHeaderValue) []NameValue {
+	out := make([]NameValue, 0, len(list))
+	for _, h := range list {
+		out = append(out, NameValue{Name: h.Name, Value: h.Value})
+	}
+	return out
+}
+
+func cookies(list []api.HttpHeaderValue) []Cookie {
+	out := make([]Cookie, 0, len(list))
+	for _, c := range list {
+		out = append(out, Cookie{Name: c.Name, Value: c.Value})
+	}
+	return out
+}
+
+// responseCookies returns the cookies set by a response, with their attributes
+// parsed from the Set-Cookie headers when available
+func responseCookies(resp api.HttpResponse) []Cookie {
+	h := http.Header{}
+	for _, v := range resp.Headers {
+		h.Add(v.Name, v.Value)
+	}
+
+	parsed := (&http.Response{Header: h}).Cookies()
+	if len(parsed) == 0 {
+		return cookies(resp.Cookies)
+	}
+
+	out := make([]Cookie, 0, len(parsed))
+	for _, c := range parsed {
+		cookie := Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.Path,
+			Domain:   c.Domain,
+			HTTPOnly: c.HttpOnly,
+			Secure:   c.Secure,
+		}
+		if !c.Expires.IsZero() {
+			cookie.Expires = c.Expires.UTC().Format(time.RFC3339)
+		}
+		out = append(out, cookie)
+	}
+	return out
+}
+
+func header(list []api.HttpHeaderValue, name string) string {
+	for _, h := range list {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func queryString(rawURL string) []NameValue {
+	out := []NameValue{}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return out
+	}
+
+	for _, pair := range strings.Split(u.RawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		name, value, _ := strings.Cut(pair, "=")
+		if n, err := url.QueryUnescape(name); err == nil {
+			name = n
+		}
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
+		out = append(out, NameValue{Name: name, Value: value})
+	}
+	return out
+}
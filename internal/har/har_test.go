@@ -0,0 +1,68 @@
+package har
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/urlquery/urlquery-cli/internal/api/apitest"
+)
+
+func TestFromReport(t *testing.T) {
+	h := FromReport(apitest.Report(), "1.0")
+
+	if h.Log.Version != "1.2" || len(h.Log.Pages) != 1 || len(h.Log.Entries) != 2 {
+		t.Fatalf("Unexpected log: %+v", h.Log)
+	}
+
+	e := h.Log.Entries[0]
+	if e.Pageref != h.Log.Pages[0].ID {
+		t.Errorf("Entry not linked to page: %s", e.Pageref)
+	}
+	if e.Request.URL != "https://login.example.com/signin?next=%2Fhome&x=1" {
+		t.Errorf("Unexpected URL %s", e.Request.URL)
+	}
+	if len(e.Request.QueryString) != 2 || e.Request.QueryString[0].Value != "/home" {
+		t.Errorf("Unexpected query string %+v", e.Request.QueryString)
+	}
+	if e.Response.Status != 302 || e.Response.RedirectURL != "https://login.example.com/home" {
+		t.Errorf("Unexpected response %+v", e.Response)
+	}
+	if e.Response.Content.Encoding != "base64" || e.Response.Content.Text != "aGVsbG8=" {
+		t.Errorf("Unexpected content %+v", e.Response.Content)
+	}
+	if c := e.Response.Cookies; len(c) != 1 || c[0].Name != "session" || !c[0].HTTPOnly || c[0].Path != "/" {
+		t.Errorf("Unexpected response cookies %+v", c)
+	}
+	if e.Time != 80 {
+		t.Errorf("Expected total time 80, got %d", e.Time)
+	}
+	if e.Timings.Blocked != -1 || e.Timings.SSL != 10 {
+		t.Errorf("Unexpected timings %+v", e.Timings)
+	}
+}
+
+func TestRequiredFields(t *testing.T) {
+	// Empty lists must be written as [] rather than null
+	r := apitest.Report()
+	r.HttpTransactions[0].Request.Cookies = nil
+	r.HttpTransactions[0].Url.Addr = "login.example.com/"
+
+	data, err := json.Marshal(FromReport(r, "dev"))
+	if err != nil {
+		t.Fatalf("Marshal error = %v", err)
+	}
+
+	var doc map[string]any
+	json.Unmarshal(data, &doc)
+	entry := doc["log"].(map[string]any)["entries"].([]any)[0].(map[string]any)
+	request := entry["request"].(map[string]any)
+
+	for _, field := range []string{"cookies", "headers", "queryString"} {
+		if _, ok := request[field].([]any); !ok {
+			t.Errorf("request.%s should be a list, got %v", field, request[field])
+		}
+	}
+	if _, ok := entry["cache"].(map[string]any); !ok {
+		t.Error("cache should be an object")
+	}
+}
@@ -0,0 +1,363 @@
+// Package index is a local search index over report JSON files: cached reports and
+// reports downloaded with 'report' or 'report all'. The index is a JSON file holding
+// the overview and the searchable terms (domains, IPs, ASNs, hashes, tags, alerts...)
+// of every report, updated incrementally when files are added, changed or removed.
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+// Format version of the index file. An index with another version is rebuilt.
+const formatVersion = 1
+
+// Indexed fields
+const (
+	FieldID      = "id"
+	FieldFqdn    = "fqdn"
+	FieldDomain  = "domain"
+	FieldIP      = "ip"
+	FieldASN     = "asn"
+	FieldCountry = "country"
+	FieldHash    = "hash"
+	FieldTag     = "tag"
+	FieldAlert   = "alert"
+	FieldTitle   = "title"
+	FieldURL     = "url"
+)
+
+// Document is an indexed report file
+type Document struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+
+	Report api.ReportOverview `json:"report"`
+
+	// Lowercase terms by field
+	Terms map[string][]string `json:"terms"`
+}
+
+// Index is a set of indexed report files, by path
+type Index struct {
+	Version   int                  `json:"version"`
+	Documents map[string]*Document `json:"documents"`
+}
+
+func New() *Index {
+	return &Index{Version: formatVersion, Documents: make(map[string]*Document)}
+}
+
+// Load reads an index file. A missing file, or an index in an older format, returns
+// an empty index.
+func Load(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	idx := New()
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("invalid index %s: %w", path, err)
+	}
+	if idx.Version != formatVersion || idx.Documents == nil {
+		return New(), nil
+	}
+	return idx, nil
+}
+
+// Save writes the index file atomically
+func (idx *Index) Save(path string) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".index-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Stats are the changes made by Update
+type Stats struct {
+	Indexed   int `json:"indexed"`
+	Unchanged int `json:"unchanged"`
+	Removed   int `json:"removed"`
+	Failed    int `json:"failed"`
+}
+
+// Update indexes the report files which are new or changed since the last update.
+// The files indexed by earlier updates are kept when they aren't in paths (e.g. a
+// directory given only once), and are updated when they changed or removed when they
+// don't exist anymore. Files which can't be read as a report are skipped and counted
+// as failed.
+func (idx *Index) Update(paths []string) Stats {
+	var stats Stats
+
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		seen[path] = true
+		idx.update(path, &stats)
+	}
+
+	for path := range idx.Documents {
+		if seen[path] {
+			continue
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			delete(idx.Documents, path)
+			stats.Removed++
+			continue
+		}
+		idx.update(path, &stats)
+	}
+	return stats
+}
+
+// update indexes a report file, unless it is unchanged
+func (idx *Index) update(path string, stats *Stats) {
+	info, err := os.Stat(path)
+	if err != nil {
+		stats.Failed++
+		return
+	}
+	if d, ok := idx.Documents[path]; ok && d.Size == info.Size() && d.Modified.Equal(info.ModTime()) {
+		stats.Unchanged++
+		return
+	}
+
+	r, err := loadReport(path)
+	if err != nil {
+		delete(idx.Documents, path)
+		stats.Failed++
+		return
+	}
+
+	d := NewDocument(r)
+	d.Path, d.Size, d.Modified = path, info.Size(), info.ModTime()
+	idx.Documents[path] = d
+	stats.Indexed++
+}
+
+// FindReports returns the report files in a directory and its subdirectories: the
+// report_<id>.json files written by 'report', and the report.json files of bundles
+func FindReports(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (strings.HasPrefix(name, ".") || name == "resources") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isReportFile(name) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	return paths, err
+}
+
+func isReportFile(name string) bool {
+	if name == "report.json" {
+		return true
+	}
+	id, ok := strings.CutPrefix(name, "report_")
+	if !ok {
+		return false
+	}
+	id, ok = strings.CutSuffix(id, ".json")
+	return ok && !strings.Contains(id, ".") // Not report_<id>.stix.json or .misp.json
+}
+
+func loadReport(path string) (*api.Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r api.Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.ID == "" {
+		return nil, fmt.Errorf("%s is not a report", path)
+	}
+	return &r, nil
+}
+
+// Search returns the reports matching the query, newest first. A report found in
+// several files (e.g. cached and downloaded) is returned once, in its latest version.
+func (idx *Index) Search(q *Query) []api.ReportOverview {
+	latest := make(map[string]*Document)
+	for _, d := range idx.Documents {
+		if prev, ok := latest[d.Report.ID]; ok && prev.Report.Version >= d.Report.Version {
+			continue
+		}
+		latest[d.Report.ID] = d
+	}
+
+	reports := []api.ReportOverview{}
+	for _, d := range latest {
+		if q.Match(d) {
+			reports = append(reports, d.Report)
+		}
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Date != reports[j].Date {
+			return reports[i].Date > reports[j].Date
+		}
+		return reports[i].ID < reports[j].ID
+	})
+	return reports
+}
+
+// Len returns the number of indexed files
+func (idx *Index) Len() int {
+	return len(idx.Documents)
+}
+
+// NewDocument extracts the searchable terms of a report
+func NewDocument(r *api.Report) *Document {
+	terms := make(map[string]map[string]bool)
+	add := func(field string, values ...string) {
+		for _, v := range values {
+			v = strings.ToLower(strings.TrimSpace(v))
+			if v == "" {
+				continue
+			}
+			if terms[field] == nil {
+				terms[field] = make(map[string]bool)
+			}
+			terms[field][v] = true
+		}
+	}
+	addURL := func(u api.URL) {
+		add(FieldURL, u.Addr)
+		add(FieldFqdn, u.Fqdn)
+		add(FieldDomain, u.Domain)
+	}
+	addIP := func(ip api.IP) {
+		add(FieldIP, ip.Addr)
+		add(FieldCountry, ip.CountryCode)
+		if ip.ASN > 0 {
+			add(FieldASN, strconv.Itoa(ip.ASN))
+		}
+	}
+	addAlerts := func(a api.Alerts) {
+		for _, alert := range a.IDSAlerts {
+			add(FieldAlert, alert.Alert)
+		}
+		for _, alert := range a.AnalyzerAlerts {
+			add(FieldAlert, alert.Alert)
+		}
+		for _, alert := range a.UrlqueryAlerts {
+			add(FieldAlert, alert.Alert)
+			add(FieldTag, alert.Tags...)
+		}
+	}
+
+	add(FieldID, r.ID)
+	add(FieldTitle, r.Final.Title)
+	add(FieldTag, r.Tags...)
+	add(FieldTag, r.Submit.Tags...)
+	addURL(r.Url)
+	addURL(r.Final.Url)
+	addIP(r.Ip)
+
+	for _, s := range r.Summary {
+		add(FieldFqdn, s.Fqdn)
+		add(FieldTag, s.Tags...)
+		addIP(s.Ip)
+	}
+
+	for _, tx := range r.HttpTransactions {
+		addURL(tx.Url)
+		addIP(tx.Ip)
+		c := tx.Response.Content
+		add(FieldHash, c.Md5, c.Sha1, c.Sha256, c.Sha512)
+		if tx.SecurityInfo != nil {
+			add(FieldHash, tx.SecurityInfo.Cert.Fingerprint.Sha1, tx.SecurityInfo.Cert.Fingerprint.Sha256)
+		}
+		addAlerts(tx.Alerts)
+	}
+
+	for _, f := range r.FileDetections {
+		add(FieldHash, f.Md5, f.Sha1, f.Sha256, f.Sha512)
+		addURL(f.Url)
+		addIP(f.Ip)
+		for _, alert := range f.Alerts.AnalyzerAlerts {
+			add(FieldAlert, alert.Alert)
+		}
+	}
+
+	for _, s := range r.Javascript.Script {
+		add(FieldHash, s.Md5, s.Sha1, s.Sha256, s.Sha512)
+		addURL(s.Url)
+		addAlerts(s.Alerts)
+	}
+	for _, list := range [][]api.JSCode{r.Javascript.Eval, r.Javascript.Write} {
+		for _, code := range list {
+			add(FieldHash, code.Md5, code.Sha1, code.Sha256, code.Sha512)
+			addAlerts(code.Alerts)
+		}
+	}
+
+	for _, s := range r.Sensors.NetworkSensors {
+		for _, alert := range s.Alerts {
+			add(FieldAlert, alert.Alert)
+		}
+	}
+	for _, s := range r.Sensors.AnalyzerSensors {
+		for _, alert := range s.Alerts {
+			add(FieldAlert, alert.Alert)
+		}
+	}
+	for _, alert := range r.Sensors.UrlQueryAlerts {
+		add(FieldAlert, alert.Alert)
+		add(FieldTag, alert.Tags...)
+	}
+
+	d := &Document{Report: r.ReportOverview, Terms: make(map[string][]string, len(terms))}
+	for field, values := range terms {
+		list := make([]string, 0, len(values))
+		for v := range values {
+			list = append(list, v)
+		}
+		sort.Strings(list)
+		d.Terms[field] = list
+	}
+	return d
+}
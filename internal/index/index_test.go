@@ -0,0 +1,107 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/urlquery/urlquery-cli/internal/api/apitest"
+)
+
+func TestQuery(t *testing.T) {
+	d := NewDocument(apitest.Report())
+
+	tests := []struct {
+		query string
+		match bool
+	}{
+		{"example.com", true},
+		{"domain:example.com", true},
+		{"domain:other.com", false},
+		{"fqdn:login.example.com tag:phishing", true},
+		{"fqdn:example.com", false},
+		{"ip:192.0.2.0/24", true},
+		{"ip:192.0.2.*", true},
+		{"asn:AS64500", true},
+		{"sha256:2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824", true},
+		{"alert:\"phishing kit\"", true},
+		{"title:sign", true},
+		{"url:*example.com/sign*", true},
+		{"https://login.example.com/signin?next=%2Fhome&x=1", true},
+		{"-tag:phishing", false},
+		{"NOT tag:benign", true},
+		{"tag:benign OR country:us", true},
+		{"tag:benign OR country:se", false},
+		{"date:2025-06", true},
+		{"date:>=2025-06-03", false},
+		{"date:2025-06-01..2025-06-02", true},
+		{"date:<2025-06-02T10:00:00Z", false},
+	}
+
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) error = %v", tt.query, err)
+			continue
+		}
+		if got := q.Match(d); got != tt.match {
+			t.Errorf("%q matched = %v, expected %v", tt.query, got, tt.match)
+		}
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	for _, query := range []string{"", "size:10", "tag:", "OR tag:x", "tag:x NOT", "date:yesterday", `title:"open`} {
+		if _, err := ParseQuery(query); err == nil {
+			t.Errorf("Expected an error for %q", query)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	dir := t.TempDir()
+	r := apitest.Report()
+	os.MkdirAll(filepath.Join(dir, r.ID, "resources"), 0755)
+	os.WriteFile(filepath.Join(dir, r.ID, "report.json"), r.Bytes(), 0644)
+	os.WriteFile(filepath.Join(dir, "report_"+r.ID+".stix.json"), []byte("{}"), 0644)
+	os.WriteFile(filepath.Join(dir, r.ID, "resources", "report.json"), []byte("{}"), 0644)
+
+	// A newer version of the same report
+	r.Version = 2
+	r.Tags = []string{"malware"}
+	os.WriteFile(filepath.Join(dir, "report_"+r.ID+".json"), r.Bytes(), 0644)
+
+	paths, err := FindReports(dir)
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("FindReports() = %v, %v", paths, err)
+	}
+
+	idx := New()
+	if stats := idx.Update(paths); stats.Indexed != 2 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+
+	q, _ := ParseQuery("domain:example.com")
+	reports := idx.Search(q)
+	if len(reports) != 1 || reports[0].Version != 2 {
+		t.Fatalf("Expected the latest version, got %+v", reports)
+	}
+
+	// Saved and loaded, unchanged files aren't indexed again
+	path := filepath.Join(dir, "index.json")
+	if err := idx.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	idx, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	// Files which aren't given anymore are kept until they are removed
+	if stats := idx.Update(paths[:1]); stats.Unchanged != 2 || stats.Removed != 0 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+	os.Remove(paths[1])
+	if stats := idx.Update(paths[:1]); stats.Unchanged != 1 || stats.Removed != 1 || idx.Len() != 1 {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
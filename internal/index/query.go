@@ -0,0 +1,381 @@
+package index
+
+import (
+	"fmt"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query is a parsed search query. Terms are field:value pairs (or bare values, matched
+// against every field), all of which must match. OR separates alternatives, and a
+// term prefixed with - or NOT must not match:
+//
+//	domain:example.com tag:phishing
+//	ip:192.0.2.0/24 OR asn:AS13335
+//	alert:"credential phishing" -tag:benign date:>=2025-06-01
+//
+// Values are matched without case, and * matches any characters. Dates are matched
+// by day, month or year (date:2025-06), compared (date:>=2025-06-01) or in a range
+// (date:2025-06-01..2025-06-30).
+type Query struct {
+	raw    string
+	groups [][]term // OR of ANDs
+}
+
+type term struct {
+	field  string // Empty for bare values
+	value  string
+	negate bool
+
+	glob   *regexp.Regexp // Values with a *
+	prefix netip.Prefix   // CIDR ranges, for the ip field
+	dates  dateRange      // For the date field
+}
+
+// queryFields maps the query field names and their aliases to the indexed fields
+var queryFields = map[string]string{
+	"id":        FieldID,
+	"report_id": FieldID,
+	"domain":    FieldDomain,
+	"fqdn":      FieldFqdn,
+	"host":      FieldFqdn,
+	"ip":        FieldIP,
+	"asn":       FieldASN,
+	"as":        FieldASN,
+	"country":   FieldCountry,
+	"hash":      FieldHash,
+	"md5":       FieldHash,
+	"sha1":      FieldHash,
+	"sha256":    FieldHash,
+	"sha512":    FieldHash,
+	"tag":       FieldTag,
+	"tags":      FieldTag,
+	"alert":     FieldAlert,
+	"alerts":    FieldAlert,
+	"title":     FieldTitle,
+	"url":       FieldURL,
+	"date":      "date",
+}
+
+// Fields returns the names accepted in queries
+func Fields() []string {
+	names := make([]string, 0, len(queryFields))
+	for name := range queryFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseQuery parses a search query
+func ParseQuery(query string) (*Query, error) {
+	words, err := split(query)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Query{raw: query}
+	var group []term
+	negate := false
+	for _, w := range words {
+		switch {
+		case w == "OR":
+			if negate || len(group) == 0 {
+				return nil, fmt.Errorf("unexpected OR in query '%s'", query)
+			}
+			q.groups = append(q.groups, group)
+			group = nil
+			continue
+		case w == "AND":
+			continue
+		case w == "NOT":
+			negate = true
+			continue
+		}
+
+		t, err := parseTerm(w)
+		if err != nil {
+			return nil, err
+		}
+		t.negate = t.negate != negate
+		negate = false
+		group = append(group, t)
+	}
+
+	if negate || len(group) == 0 {
+		return nil, fmt.Errorf("incomplete query '%s'", query)
+	}
+	q.groups = append(q.groups, group)
+	return q, nil
+}
+
+func parseTerm(word string) (term, error) {
+	var t term
+	if strings.HasPrefix(word, "-") && len(word) > 1 {
+		t.negate = true
+		word = word[1:]
+	}
+
+	field, value, ok := strings.Cut(word, ":")
+	if !ok || strings.ContainsAny(field, "/.") || strings.HasPrefix(value, "//") {
+		// A bare value (which may contain a colon, e.g. an IPv6 address or a URL)
+		t.value = trimScheme(unquote(word))
+		if strings.Contains(t.value, "*") {
+			t.glob = compileGlob(t.value, true)
+		}
+		return t, nil
+	}
+
+	name, known := queryFields[strings.ToLower(field)]
+	if !known {
+		return t, fmt.Errorf("unknown field '%s' (available: %s)", field, strings.Join(Fields(), ", "))
+	}
+	t.field = name
+	t.value = unquote(value)
+	if t.value == "" {
+		return t, fmt.Errorf("missing value for '%s'", field)
+	}
+
+	switch name {
+	case "date":
+		dates, err := parseDateRange(strings.ToUpper(t.value))
+		if err != nil {
+			return t, err
+		}
+		t.dates = dates
+		return t, nil
+	case FieldURL:
+		t.value = trimScheme(t.value)
+	case FieldASN:
+		t.value = strings.TrimPrefix(t.value, "as")
+	case FieldIP:
+		if prefix, err := netip.ParsePrefix(t.value); err == nil {
+			t.prefix = prefix
+		}
+	}
+	if strings.Contains(t.value, "*") {
+		contains := name == FieldAlert || name == FieldTitle || name == FieldURL
+		t.glob = compileGlob(t.value, contains)
+	}
+	return t, nil
+}
+
+// split splits a query into words, keeping quoted values (with their quotes) together
+func split(query string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	quoted := false
+
+	for _, r := range query {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			word.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		default:
+			word.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in query '%s'", query)
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func unquote(s string) string {
+	return strings.ToLower(strings.ReplaceAll(s, `"`, ""))
+}
+
+// trimScheme removes the scheme of URLs, which isn't part of the indexed addresses
+func trimScheme(value string) string {
+	if _, rest, ok := strings.Cut(value, "://"); ok {
+		return rest
+	}
+	return value
+}
+
+func (q *Query) String() string {
+	return q.raw
+}
+
+// Match reports if the document matches the query
+func (q *Query) Match(d *Document) bool {
+	for _, group := range q.groups {
+		if matchAll(group, d) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAll(terms []term, d *Document) bool {
+	for _, t := range terms {
+		if t.match(d) == t.negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (t term) match(d *Document) bool {
+	switch t.field {
+	case "":
+		return t.matchBare(d)
+	case "date":
+		return t.dates.contains(d.Report.Date)
+	case FieldDomain:
+		return t.matchAny(d.Terms[FieldDomain], t.matchExact) || t.matchAny(d.Terms[FieldFqdn], t.matchDomain)
+	case FieldIP:
+		return t.matchAny(d.Terms[FieldIP], t.matchIP)
+	case FieldAlert, FieldTitle, FieldURL:
+		return t.matchAny(d.Terms[t.field], t.matchContains)
+	default:
+		return t.matchAny(d.Terms[t.field], t.matchExact)
+	}
+}
+
+// matchBare matches a bare value against every field: exact values, domains, and
+// the text of URLs, titles and alerts
+func (t term) matchBare(d *Document) bool {
+	for field, values := range d.Terms {
+		match := t.matchExact
+		switch field {
+		case FieldAlert, FieldTitle, FieldURL:
+			match = t.matchContains
+		case FieldFqdn:
+			match = t.matchDomain
+		}
+		if t.matchAny(values, match) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t term) matchAny(values []string, match func(value string) bool) bool {
+	for _, v := range values {
+		if match(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t term) matchExact(value string) bool {
+	if t.glob != nil {
+		return t.glob.MatchString(value)
+	}
+	return value == t.value
+}
+
+// matchDomain matches the domain or its subdomains
+func (t term) matchDomain(value string) bool {
+	return t.matchExact(value) || strings.HasSuffix(value, "."+t.value)
+}
+
+func (t term) matchContains(value string) bool {
+	if t.glob != nil {
+		return t.glob.MatchString(value)
+	}
+	return strings.Contains(value, t.value)
+}
+
+// matchIP matches an address or a CIDR range
+func (t term) matchIP(value string) bool {
+	if t.prefix.IsValid() {
+		addr, err := netip.ParseAddr(value)
+		return err == nil && t.prefix.Contains(addr)
+	}
+	return t.matchExact(value)
+}
+
+// compileGlob compiles a value where * matches any characters (including /). Values
+// matched as text also match in the middle of the text.
+func compileGlob(value string, contains bool) *regexp.Regexp {
+	expr := strings.ReplaceAll(regexp.QuoteMeta(value), `\*`, ".*")
+	if !contains {
+		expr = "^" + expr + "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// dateRange is a time range [from, to). A zero bound is unbounded.
+type dateRange struct {
+	from, to time.Time
+}
+
+// Date layouts accepted in queries, from the most precise
+var dateLayouts = []struct {
+	layout string
+	next   func(t time.Time) time.Time
+}{
+	{time.RFC3339, func(t time.Time) time.Time { return t.Add(time.Second) }},
+	{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	{"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+}
+
+// parsePeriod returns the period covered by a date, e.g. the whole day of 2025-06-02
+func parsePeriod(value string) (dateRange, error) {
+	for _, l := range dateLayouts {
+		if t, err := time.Parse(l.layout, value); err == nil {
+			return dateRange{from: t, to: l.next(t)}, nil
+		}
+	}
+	return dateRange{}, fmt.Errorf("invalid date '%s' (expected YYYY, YYYY-MM, YYYY-MM-DD or RFC3339)", value)
+}
+
+func parseDateRange(value string) (dateRange, error) {
+	if from, to, ok := strings.Cut(value, ".."); ok {
+		start, err := parsePeriod(from)
+		if err != nil {
+			return dateRange{}, err
+		}
+		end, err := parsePeriod(to)
+		if err != nil {
+			return dateRange{}, err
+		}
+		return dateRange{from: start.from, to: end.to}, nil
+	}
+
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		rest, ok := strings.CutPrefix(value, op)
+		if !ok {
+			continue
+		}
+		p, err := parsePeriod(rest)
+		if err != nil {
+			return dateRange{}, err
+		}
+		switch op {
+		case ">=":
+			return dateRange{from: p.from}, nil
+		case ">":
+			return dateRange{from: p.to}, nil
+		case "<=":
+			return dateRange{to: p.to}, nil
+		default:
+			return dateRange{to: p.from}, nil
+		}
+	}
+	return parsePeriod(value)
+}
+
+func (r dateRange) contains(date string) bool {
+	t, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return false
+	}
+	return (r.from.IsZero() || !t.Before(r.from)) && (r.to.IsZero() || t.Before(r.to))
+}
@@ -0,0 +1,125 @@
+// Package integrity verifies downloaded content against the hashes it is expected to have.
+package integrity
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+// Digest holds the hex encoded md5, sha1 and sha256 hashes of some content.
+// Empty hashes are unknown, and not checked by Verify.
+type Digest struct {
+	MD5    string `json:"md5,omitempty"`
+	SHA1   string `json:"sha1,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// MismatchError is returned when content doesn't have the expected hash
+type MismatchError struct {
+	Algorithm string
+	Expected  string
+	Actual    string
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("%s mismatch: expected %s, got %s", e.Algorithm, e.Expected, e.Actual)
+}
+
+// Hasher computes the Digest of everything written to it
+type Hasher struct {
+	md5, sha1, sha256 hash.Hash
+	size              int64
+}
+
+func NewHasher() *Hasher {
+	return &Hasher{md5: md5.New(), sha1: sha1.New(), sha256: sha256.New()}
+}
+
+func (h *Hasher) Write(p []byte) (int, error) {
+	h.md5.Write(p)
+	h.sha1.Write(p)
+	h.sha256.Write(p)
+	h.size += int64(len(p))
+	return len(p), nil
+}
+
+// Size returns the number of bytes written
+func (h *Hasher) Size() int64 {
+	return h.size
+}
+
+func (h *Hasher) Digest() Digest {
+	return Digest{
+		MD5:    hex.EncodeToString(h.md5.Sum(nil)),
+		SHA1:   hex.EncodeToString(h.sha1.Sum(nil)),
+		SHA256: hex.EncodeToString(h.sha256.Sum(nil)),
+	}
+}
+
+// Sum returns the Digest of data
+func Sum(data []byte) Digest {
+	h := NewHasher()
+	h.Write(data)
+	return h.Digest()
+}
+
+// Match checks a single hash, with the algorithm chosen by its length
+// (32: md5, 40: sha1, 64: sha256)
+func (d Digest) Match(hash string) error {
+	var expected Digest
+	switch len(hash) {
+	case 32:
+		expected.MD5 = hash
+	case 40:
+		expected.SHA1 = hash
+	case 64:
+		expected.SHA256 = hash
+	default:
+		return fmt.Errorf("unsupported hash '%s' (expected md5, sha1 or sha256)", hash)
+	}
+	return d.Verify(expected)
+}
+
+// Verify checks every hash known in expected
+func (d Digest) Verify(expected Digest) error {
+	checks := []struct{ algorithm, expected, actual string }{
+		{"md5", expected.MD5, d.MD5},
+		{"sha1", expected.SHA1, d.SHA1},
+		{"sha256", expected.SHA256, d.SHA256},
+	}
+	for _, c := range checks {
+		if c.expected != "" && !strings.EqualFold(c.expected, c.actual) {
+			return &MismatchError{Algorithm: c.algorithm, Expected: strings.ToLower(c.expected), Actual: c.actual}
+		}
+	}
+	return nil
+}
+
+// FromReport returns the hashes a report lists for the resource with the given hash
+// (of any algorithm), from the HTTP responses or the files. Returns false if the
+// report doesn't list the resource.
+func FromReport(r *api.Report, hash string) (Digest, bool) {
+	matches := func(d Digest) bool {
+		return hash != "" && (strings.EqualFold(d.MD5, hash) || strings.EqualFold(d.SHA1, hash) || strings.EqualFold(d.SHA256, hash))
+	}
+
+	for _, tx := range r.HttpTransactions {
+		c := tx.Response.Content
+		if d := (Digest{MD5: c.Md5, SHA1: c.Sha1, SHA256: c.Sha256}); matches(d) {
+			return d, true
+		}
+	}
+	for _, f := range r.FileDetections {
+		if d := (Digest{MD5: f.Md5, SHA1: f.Sha1, SHA256: f.Sha256}); matches(d) {
+			return d, true
+		}
+	}
+	return Digest{}, false
+}
@@ -0,0 +1,52 @@
+package integrity
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+const (
+	helloMD5    = "5d41402abc4b2a76b9719d911017c592"
+	helloSHA1   = "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"
+	helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+)
+
+func TestMatch(t *testing.T) {
+	d := Sum([]byte("hello"))
+
+	for _, hash := range []string{helloMD5, helloSHA1, helloSHA256, "2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824"} {
+		if err := d.Match(hash); err != nil {
+			t.Errorf("Match(%s) error = %v", hash, err)
+		}
+	}
+
+	var mismatch *MismatchError
+	if err := d.Match("00000000000000000000000000000000"); !errors.As(err, &mismatch) || mismatch.Algorithm != "md5" {
+		t.Errorf("Expected an md5 mismatch, got %v", err)
+	}
+	if err := d.Match("abc"); err == nil || errors.As(err, &mismatch) {
+		t.Errorf("Expected an unsupported hash error, got %v", err)
+	}
+}
+
+func TestFromReport(t *testing.T) {
+	r := &api.Report{}
+	tx := api.HttpTransaction{}
+	tx.Response.Content = api.HttpContent{Md5: helloMD5, Sha1: "bad", Sha256: helloSHA256}
+	r.HttpTransactions = []api.HttpTransaction{tx}
+
+	expected, ok := FromReport(r, helloSHA256)
+	if !ok || expected.MD5 != helloMD5 {
+		t.Fatalf("Unexpected digest %+v", expected)
+	}
+	// The report lists another sha1 than the content has
+	if err := Sum([]byte("hello")).Verify(expected); err == nil {
+		t.Errorf("Expected a sha1 mismatch")
+	}
+
+	if _, ok := FromReport(r, helloSHA1); ok {
+		t.Errorf("Unexpected match for a hash not in the report")
+	}
+}
@@ -0,0 +1,195 @@
+// Package ioc extracts indicators of compromise from urlquery reports.
+package ioc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+// Indicator types
+const (
+	TypeURL        = "url"
+	TypeDomain     = "domain"
+	TypeIP         = "ip"
+	TypeASN        = "asn"
+	TypeMD5        = "md5"
+	TypeSHA1       = "sha1"
+	TypeSHA256     = "sha256"
+	TypeCertSHA1   = "cert_sha1"
+	TypeCertSHA256 = "cert_sha256"
+)
+
+// Indicator is a unique indicator found in a report
+type Indicator struct {
+	Type    string   `json:"type"`
+	Value   string   `json:"value"`
+	Sources []string `json:"sources"`          // Parts of the report the indicator was found in
+	Alerts  []string `json:"alerts,omitempty"` // Alerts triggered where the indicator was found
+}
+
+func (i Indicator) Columns() []string {
+	return []string{"type", "value", "sources", "alerts"}
+}
+
+func (i Indicator) Rows() [][]string {
+	return [][]string{{i.Type, i.Value, strings.Join(i.Sources, " "), strings.Join(i.Alerts, "; ")}}
+}
+
+// collector de-duplicates indicators while keeping the order they were found in
+type collector struct {
+	indicators []*Indicator
+	index      map[string]*Indicator
+}
+
+func (c *collector) add(typ, value, source string, alerts []string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	if typ != TypeURL && typ != TypeASN {
+		value = strings.ToLower(value)
+	}
+
+	key := typ + "|" + value
+	ind, ok := c.index[key]
+	if !ok {
+		ind = &Indicator{Type: typ, Value: value}
+		c.index[key] = ind
+		c.indicators = append(c.indicators, ind)
+	}
+	ind.Sources = appendUnique(ind.Sources, source)
+	for _, alert := range alerts {
+		ind.Alerts = appendUnique(ind.Alerts, alert)
+	}
+}
+
+func (c *collector) addURL(u api.URL, source string, alerts []string) {
+	if u.Addr != "" {
+		c.add(TypeURL, FullURL(u), source, alerts)
+	}
+	c.add(TypeDomain, u.Fqdn, source, alerts)
+}
+
+func (c *collector) addIP(ip api.IP, source string, alerts []string) {
+	c.add(TypeIP, ip.Addr, source, alerts)
+	if ip.ASN > 0 {
+		c.add(TypeASN, fmt.Sprintf("AS%d", ip.ASN), source, nil)
+	}
+}
+
+func (c *collector) addHashes(md5, sha1, sha256, source string, alerts []string) {
+	c.add(TypeMD5, md5, source, alerts)
+	c.add(TypeSHA1, sha1, source, alerts)
+	c.add(TypeSHA256, sha256, source, alerts)
+}
+
+// Extract returns the unique indicators found in a report: URLs, domains, IPs and
+// ASNs of the requests, hashes of the responses, files and scripts, and the
+// fingerprints of the TLS certificates. Every indicator carries the alerts which
+// were triggered by the requests, files or scripts it was found in.
+func Extract(r *api.Report) []Indicator {
+	c := &collector{index: make(map[string]*Indicator)}
+
+	reportAlerts := urlqueryAlerts(r.Sensors.UrlQueryAlerts)
+	c.addURL(r.Url, "submitted", reportAlerts)
+	c.addIP(r.Ip, "submitted", nil)
+	c.addURL(r.Final.Url, "final", reportAlerts)
+
+	for _, s := range r.Summary {
+		c.add(TypeDomain, s.Fqdn, "summary", nil)
+		c.addIP(s.Ip, "summary", nil)
+	}
+
+	for _, tx := range r.HttpTransactions {
+		alerts := FormatAlerts(tx.Alerts)
+		c.addURL(tx.Url, "http", alerts)
+		c.addIP(tx.Ip, "http", alerts)
+
+		content := tx.Response.Content
+		c.addHashes(content.Md5, content.Sha1, content.Sha256, "http", alerts)
+
+		if tx.SecurityInfo != nil {
+			fp := tx.SecurityInfo.Cert.Fingerprint
+			c.add(TypeCertSHA1, fp.Sha1, "certificate", nil)
+			c.add(TypeCertSHA256, fp.Sha256, "certificate", nil)
+		}
+	}
+
+	for _, f := range r.FileDetections {
+		alerts := analyzerAlerts(f.Alerts.AnalyzerAlerts)
+		c.addURL(f.Url, "file", alerts)
+		c.addIP(f.Ip, "file", alerts)
+		c.addHashes(f.Md5, f.Sha1, f.Sha256, "file", alerts)
+	}
+
+	for _, s := range r.Javascript.Script {
+		alerts := FormatAlerts(s.Alerts)
+		if !s.IsInline {
+			c.addURL(s.Url, "javascript", alerts)
+		}
+		c.addHashes(s.Md5, s.Sha1, s.Sha256, "javascript", alerts)
+	}
+	for _, code := range [][]api.JSCode{r.Javascript.Eval, r.Javascript.Write} {
+		for _, js := range code {
+			c.addHashes(js.Md5, js.Sha1, js.Sha256, "javascript", FormatAlerts(js.Alerts))
+		}
+	}
+
+	for _, sensor := range r.Sensors.NetworkSensors {
+		for _, alert := range sensor.Alerts {
+			c.add(TypeIP, alert.IpDst.Addr, "ids", []string{"ids: " + alert.Alert})
+		}
+	}
+
+	indicators := make([]Indicator, len(c.indicators))
+	for i, ind := range c.indicators {
+		indicators[i] = *ind
+	}
+	return indicators
+}
+
+// FullURL returns the URL including its schema
+func FullURL(u api.URL) string {
+	if u.Schema == "" || strings.Contains(u.Addr, "://") {
+		return u.Addr
+	}
+	return u.Schema + "://" + u.Addr
+}
+
+// FormatAlerts returns a description of every alert, prefixed by the kind of sensor
+func FormatAlerts(a api.Alerts) []string {
+	var alerts []string
+	for _, alert := range a.IDSAlerts {
+		alerts = append(alerts, "ids: "+alert.Alert)
+	}
+	alerts = append(alerts, analyzerAlerts(a.AnalyzerAlerts)...)
+	alerts = append(alerts, urlqueryAlerts(a.UrlqueryAlerts)...)
+	return alerts
+}
+
+func analyzerAlerts(list []api.AnalyzerAlert) []string {
+	var alerts []string
+	for _, alert := range list {
+		alerts = append(alerts, fmt.Sprintf("analyzer/%s: %s", alert.SensorName, alert.Alert))
+	}
+	return alerts
+}
+
+func urlqueryAlerts(list []api.UrlqueryAlert) []string {
+	var alerts []string
+	for _, alert := range list {
+		alerts = append(alerts, "urlquery: "+alert.Alert)
+	}
+	return alerts
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
@@ -0,0 +1,59 @@
+package ioc
+
+import (
+	"testing"
+
+	"github.com/urlquery/urlquery-cli/internal/api/apitest"
+)
+
+func find(indicators []Indicator, typ, value string) *Indicator {
+	for i := range indicators {
+		if indicators[i].Type == typ && indicators[i].Value == value {
+			return &indicators[i]
+		}
+	}
+	return nil
+}
+
+func TestExtract(t *testing.T) {
+	indicators := Extract(apitest.Report())
+
+	tests := []struct {
+		typ, value string
+		alerts     int
+	}{
+		{TypeURL, "https://login.example.com/signin?next=%2Fhome&x=1", 2},
+		{TypeDomain, "login.example.com", 2},
+		{TypeIP, "192.0.2.10", 1},
+		{TypeASN, "AS64500", 0},
+		{TypeSHA256, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", 1},
+		{TypeURL, "https://cdn.example.net/kit.js", 2},
+		{TypeDomain, "cdn.example.net", 2},
+		{TypeIP, "198.51.100.7", 2},
+		{TypeASN, "AS64501", 0},
+		{TypeMD5, "d41d8cd98f00b204e9800998ecf8427e", 2},
+		{TypeSHA256, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", 3},
+		{TypeCertSHA1, "aa:bb", 0},
+		{TypeCertSHA256, "cc:dd", 0},
+	}
+
+	for _, tt := range tests {
+		ind := find(indicators, tt.typ, tt.value)
+		if ind == nil {
+			t.Errorf("Missing indicator %s %s", tt.typ, tt.value)
+			continue
+		}
+		if len(ind.Alerts) != tt.alerts {
+			t.Errorf("Indicator %s %s: expected %d alerts, got %q", tt.typ, tt.value, tt.alerts, ind.Alerts)
+		}
+	}
+
+	if len(indicators) != len(tests) {
+		t.Errorf("Expected %d unique indicators, got %d", len(tests), len(indicators))
+	}
+
+	domain := find(indicators, TypeDomain, "login.example.com")
+	if len(domain.Sources) != 4 {
+		t.Errorf("Expected domain to be found in submitted, final, summary and http, got %q", domain.Sources)
+	}
+}
@@ -0,0 +1,230 @@
+// Package misp converts urlquery reports to MISP events, in the JSON format
+// accepted by the MISP event import (Events > Import from... > MISP JSON).
+package misp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/ioc"
+	"github.com/urlquery/urlquery-cli/internal/output"
+)
+
+// MISP enumerations, as strings like in the MISP API
+const (
+	ThreatLevelHigh      = "1"
+	ThreatLevelMedium    = "2"
+	ThreatLevelLow       = "3"
+	ThreatLevelUndefined = "4"
+
+	AnalysisCompleted = "2"
+
+	DistributionOrganisation = "0" // Your organisation only
+	DistributionInherit      = "5" // Inherit from the event
+)
+
+// Namespace of the event and attribute UUIDs, which are derived from the report
+// so importing the same report twice updates the event instead of duplicating it
+var namespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://urlquery.net/misp"))
+
+// Wrapper is the top level object of an exported event
+type Wrapper struct {
+	Event *Event `json:"Event"`
+}
+
+type Event struct {
+	UUID          string      `json:"uuid"`
+	Info          string      `json:"info"`
+	Date          string      `json:"date"`
+	Timestamp     string      `json:"timestamp"`
+	ThreatLevelID string      `json:"threat_level_id"`
+	Analysis      string      `json:"analysis"`
+	Distribution  string      `json:"distribution"`
+	Published     bool        `json:"published"`
+	Tag           []Tag       `json:"Tag"`
+	Attribute     []Attribute `json:"Attribute"`
+}
+
+type Tag struct {
+	Name string `json:"name"`
+}
+
+type Attribute struct {
+	UUID         string `json:"uuid"`
+	Type         string `json:"type"`
+	Category     string `json:"category"`
+	Value        string `json:"value"`
+	ToIDS        bool   `json:"to_ids"`
+	Comment      string `json:"comment"`
+	Distribution string `json:"distribution"`
+	Timestamp    string `json:"timestamp"`
+}
+
+// Attribute type and category of each indicator type
+var attributeTypes = map[string][2]string{
+	ioc.TypeURL:        {"url", "Network activity"},
+	ioc.TypeDomain:     {"domain", "Network activity"},
+	ioc.TypeIP:         {"ip-dst", "Network activity"},
+	ioc.TypeASN:        {"AS", "Network activity"},
+	ioc.TypeMD5:        {"md5", "Payload delivery"},
+	ioc.TypeSHA1:       {"sha1", "Payload delivery"},
+	ioc.TypeSHA256:     {"sha256", "Payload delivery"},
+	ioc.TypeCertSHA1:   {"x509-fingerprint-sha1", "Network activity"},
+	ioc.TypeCertSHA256: {"x509-fingerprint-sha256", "Network activity"},
+}
+
+// FromReport builds an event with an attribute for every indicator of the report.
+// Indicators involved in alerts are flagged for IDS export, and the sensors which
+// raised the alerts are listed in the attribute comment.
+func FromReport(r *api.Report) *Event {
+	date, err := time.Parse(time.RFC3339Nano, r.Date)
+	if err != nil {
+		date = time.Unix(0, 0)
+	}
+	date = date.UTC()
+	timestamp := strconv.FormatInt(date.Unix(), 10)
+
+	eventUUID := uuid.NewSHA1(namespace, []byte(r.ID))
+	e := &Event{
+		UUID:          eventUUID.String(),
+		Info:          fmt.Sprintf("urlquery.net report %s: %s", r.ID, ioc.FullURL(r.Url)),
+		Date:          date.Format("2006-01-02"),
+		Timestamp:     timestamp,
+		ThreatLevelID: ThreatLevelUndefined,
+		Analysis:      AnalysisCompleted,
+		Distribution:  DistributionOrganisation,
+		Tag:           tags(r.ReportOverview),
+		Attribute:     []Attribute{},
+	}
+
+	alerts := r.Stats.AlertCount
+	if alerts.Ids+alerts.Urlquery+alerts.Analyzer > 0 {
+		e.ThreatLevelID = ThreatLevelMedium
+	}
+
+	attribute := func(typ, category, value, comment string, toIDS bool) {
+		e.Attribute = append(e.Attribute, Attribute{
+			UUID:         uuid.NewSHA1(eventUUID, []byte(typ+"|"+value)).String(),
+			Type:         typ,
+			Category:     category,
+			Value:        value,
+			ToIDS:        toIDS,
+			Comment:      comment,
+			Distribution: DistributionInherit,
+			Timestamp:    timestamp,
+		})
+	}
+
+	if r.ID != "" {
+		attribute("link", "External analysis", "https://urlquery.net/report/"+r.ID, "urlquery.net report", false)
+	}
+
+	for _, ind := range ioc.Extract(r) {
+		t, ok := attributeTypes[ind.Type]
+		if !ok {
+			continue
+		}
+		attribute(t[0], t[1], ind.Value, comment(ind), len(ind.Alerts) > 0)
+	}
+
+	return e
+}
+
+// tags returns the report tags as urlquery:tag="<tag>", and the tags given at
+// submission as they are (they may already be MISP tags, e.g. tlp:amber)
+func tags(o api.ReportOverview) []Tag {
+	list := []Tag{}
+	seen := map[string]bool{}
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			list = append(list, Tag{Name: name})
+		}
+	}
+
+	for _, tag := range o.Tags {
+		add(fmt.Sprintf("urlquery:tag=%q", tag))
+	}
+	for _, tag := range o.Submit.Tags {
+		add(tag)
+	}
+	return list
+}
+
+// comment lists the sensors which raised alerts involving the indicator, or the
+// parts of the report the indicator was found in if there are none
+func comment(ind ioc.Indicator) string {
+	var sensors []string
+	for _, alert := range ind.Alerts {
+		sensor, _, _ := strings.Cut(alert, ":")
+		sensors = appendUnique(sensors, sensor)
+	}
+	if len(sensors) > 0 {
+		return "Alerts: " + strings.Join(sensors, ", ")
+	}
+	return "Found in: " + strings.Join(ind.Sources, ", ")
+}
+
+func appendUnique(list []string, value string) []string {
+	for _, v := range list {
+		if v == value {
+			return list
+		}
+	}
+	return append(list, value)
+}
+
+func init() {
+	output.Register("misp", func(string) (output.Formatter, error) { return formatter{}, nil })
+}
+
+// formatter writes reports and search results as MISP events. A single report is
+// written as one event, lists of reports in the {"response": [...]} format of the MISP API.
+type formatter struct{}
+
+func (formatter) Format(w io.Writer, v any) error {
+	var out any
+
+	if l, ok := v.(output.Lister); ok {
+		events := []Wrapper{}
+		for _, item := range l.Items() {
+			e, err := event(item)
+			if err != nil {
+				return err
+			}
+			events = append(events, Wrapper{Event: e})
+		}
+		out = map[string]any{"response": events}
+	} else {
+		e, err := event(v)
+		if err != nil {
+			return err
+		}
+		out = Wrapper{Event: e}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func event(v any) (*Event, error) {
+	switch r := v.(type) {
+	case *api.Report:
+		return FromReport(r), nil
+	case api.Report:
+		return FromReport(&r), nil
+	case *api.ReportOverview:
+		return FromReport(&api.Report{ReportOverview: *r}), nil
+	case api.ReportOverview:
+		return FromReport(&api.Report{ReportOverview: r}), nil
+	}
+	return nil, fmt.Errorf("the misp format only supports reports and search results (got %T)", v)
+}
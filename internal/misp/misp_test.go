@@ -0,0 +1,72 @@
+package misp
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/api/apitest"
+	"github.com/urlquery/urlquery-cli/internal/output"
+)
+
+func TestFromReport(t *testing.T) {
+	e := FromReport(apitest.Report())
+
+	if e.Date != "2025-06-02" || e.Timestamp != "1748858400" || e.ThreatLevelID != ThreatLevelMedium {
+		t.Errorf("Unexpected event %+v", e)
+	}
+	if len(e.Tag) != 3 || e.Tag[0].Name != `urlquery:tag="phishing"` || e.Tag[1].Name != "tlp:amber" || e.Tag[2].Name != "phishing" {
+		t.Errorf("Unexpected tags %+v", e.Tag)
+	}
+
+	attrs := map[string]Attribute{}
+	for _, a := range e.Attribute {
+		attrs[a.Type+" "+a.Value] = a
+	}
+
+	sha := attrs["sha256 e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"]
+	if !sha.ToIDS || sha.Comment != "Alerts: ids, analyzer/yara" || sha.Category != "Payload delivery" {
+		t.Errorf("Unexpected hash attribute %+v", sha)
+	}
+	as := attrs["AS AS64500"]
+	if as.ToIDS || as.Comment != "Found in: submitted, summary, http" {
+		t.Errorf("Unexpected AS attribute %+v", as)
+	}
+	if _, ok := attrs["link https://urlquery.net/report/"+apitest.Report().ID]; !ok {
+		t.Errorf("Missing report link in %v", attrs)
+	}
+
+	// UUIDs are derived from the report
+	again := FromReport(apitest.Report())
+	if again.UUID != e.UUID || again.Attribute[1].UUID != e.Attribute[1].UUID {
+		t.Errorf("UUIDs are not deterministic")
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	f, err := output.New("misp")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	search := &api.SearchReportResponse{Reports: []api.ReportOverview{apitest.Report().ReportOverview}}
+	var buf bytes.Buffer
+	if err := f.Format(&buf, search); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+
+	var out struct {
+		Response []Wrapper `json:"response"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("Invalid output: %v", err)
+	}
+	if len(out.Response) != 1 || out.Response[0].Event.UUID != FromReport(apitest.Report()).UUID {
+		t.Errorf("Unexpected output %s", buf.String())
+	}
+
+	if err := f.Format(&buf, api.ReputationResult{}); err == nil {
+		t.Errorf("Expected an error for unsupported results")
+	}
+}
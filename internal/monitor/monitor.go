@@ -0,0 +1,299 @@
+// Package monitor re-scans a watchlist of URLs periodically, and reports what changed
+// between two scans of the same URL. The state of every URL (pending submission, last
+// report, verdict) is kept in a file, so a restarted monitor resumes waiting for the
+// pending submissions instead of submitting them again.
+package monitor
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/diff"
+	"github.com/urlquery/urlquery-cli/internal/ioc"
+)
+
+// Event types
+const (
+	EventSubmitted       = "submitted"
+	EventChecked         = "checked"
+	EventVerdictChanged  = "verdict_changed"
+	EventFinalURLChanged = "final_url_changed"
+	EventAlertsChanged   = "alert_count_changed"
+	EventError           = "error"
+)
+
+// Event is emitted for every submission, completed scan, change and error
+type Event struct {
+	Time             time.Time `json:"time"`
+	Type             string    `json:"type"`
+	URL              string    `json:"url"`
+	ReportID         string    `json:"report_id,omitempty"`
+	PreviousReportID string    `json:"previous_report_id,omitempty"`
+
+	// Previous and new value of a change
+	From any `json:"from,omitempty"`
+	To   any `json:"to,omitempty"`
+
+	// Result of a completed scan, and the changes since the previous report
+	Verdict string           `json:"verdict,omitempty"`
+	Alerts  *api.AlertCounts `json:"alerts,omitempty"`
+	Diff    *diff.Diff       `json:"diff,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+func (e Event) Columns() []string {
+	return []string{"time", "type", "url", "report_id", "previous_report_id", "verdict", "from", "to", "error"}
+}
+
+func (e Event) Rows() [][]string {
+	str := func(v any) string {
+		switch v := v.(type) {
+		case nil:
+			return ""
+		case string:
+			return v
+		case api.AlertCounts:
+			return "urlquery=" + strconv.Itoa(v.Urlquery) + " ids=" + strconv.Itoa(v.Ids) + " analyzer=" + strconv.Itoa(v.Analyzer)
+		}
+		return ""
+	}
+	return [][]string{{e.Time.Format(time.RFC3339), e.Type, e.URL, e.ReportID, e.PreviousReportID, e.Verdict, str(e.From), str(e.To), e.Error}}
+}
+
+// Client is the part of the API used by the monitor
+type Client interface {
+	Submit(ctx context.Context, submit api.SubmitJob) (*api.QueuedJob, error)
+	WaitForReport(ctx context.Context, queue_id string, onStatus func(*api.QueuedJob)) (*api.QueuedJob, error)
+	GetReport(ctx context.Context, report_id string) (*api.Report, error)
+	CheckReputation(ctx context.Context, query string) (*api.ReputationResult, error)
+}
+
+// Monitor scans the due URLs of a watchlist
+type Monitor struct {
+	Client Client
+	Store  *Store
+
+	// Submission settings (user agent, referer...) of every URL. The access and tags
+	// of the watchlist entries are applied to it.
+	Job api.SubmitJob
+
+	// Maximum time to wait for a report (0 for no limit), and number of URLs scanned at once
+	Timeout time.Duration
+	Workers int
+
+	// OnEvent is called for every event, one at a time, and holds up the other events
+	// until it returns
+	OnEvent func(Event)
+
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+func (m *Monitor) clock() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
+
+func (m *Monitor) emit(e Event) {
+	if m.OnEvent == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = m.clock().UTC()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.OnEvent(e)
+}
+
+// Due returns the entries to scan: the ones with a pending submission, never scanned,
+// or submitted at least an interval ago
+func (m *Monitor) Due(w *Watchlist) []Entry {
+	now := m.clock()
+
+	var due []Entry
+	for _, e := range w.URLs {
+		st := m.Store.Get(e.URL)
+		if st.QueueID != "" || st.Submitted.IsZero() || !now.Before(st.Submitted.Add(time.Duration(e.Interval))) {
+			due = append(due, e)
+		}
+	}
+	return due
+}
+
+// RunOnce scans the due entries of the watchlist, and returns when they are done
+func (m *Monitor) RunOnce(ctx context.Context, w *Watchlist) {
+	entries := make(chan Entry)
+	var wg sync.WaitGroup
+	for i := 0; i < max(m.Workers, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range entries {
+				m.check(ctx, e)
+			}
+		}()
+	}
+
+	for _, e := range m.Due(w) {
+		if ctx.Err() != nil {
+			break
+		}
+		entries <- e
+	}
+	close(entries)
+	wg.Wait()
+}
+
+// Run scans the due entries every tick until the context is cancelled. The watchlist
+// is loaded again before every round, so changes apply without a restart; when it
+// can't be loaded, the previous one is kept.
+func (m *Monitor) Run(ctx context.Context, load func() (*Watchlist, error), tick time.Duration) error {
+	w, err := load()
+	if err != nil {
+		return err
+	}
+
+	for {
+		m.RunOnce(ctx, w)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(tick):
+		}
+
+		if next, err := load(); err != nil {
+			m.emit(Event{Type: EventError, Error: err.Error()})
+		} else {
+			w = next
+		}
+	}
+}
+
+// check submits the URL (or resumes waiting for its pending submission), and compares
+// the report with the previous one
+func (m *Monitor) check(ctx context.Context, e Entry) {
+	st := m.Store.Get(e.URL)
+
+	// A submission pending for longer than the interval is given up
+	if st.QueueID != "" && m.clock().Sub(st.Submitted) > time.Duration(e.Interval) {
+		st.QueueID = ""
+	}
+
+	if st.QueueID == "" {
+		job := m.Job
+		job.Url = e.URL
+		job.Tags = mergeTags(job.Tags, e.Tags)
+		if e.Access != "" {
+			job.Access = e.Access
+		}
+
+		queued, err := m.Client.Submit(ctx, job)
+		if err != nil {
+			// Retried after the interval
+			m.fail(e.URL, err, func(st *URLState) { st.QueueID, st.Submitted = "", m.clock() })
+			return
+		}
+
+		st.QueueID = queued.QueueID
+		m.update(e.URL, func(s *URLState) {
+			s.QueueID, s.Submitted, s.LastError = queued.QueueID, m.clock(), ""
+		})
+		m.emit(Event{Type: EventSubmitted, URL: e.URL, ReportID: queued.ReportID})
+	}
+
+	waitCtx, cancel := ctx, context.CancelFunc(func() {})
+	if m.Timeout > 0 {
+		waitCtx, cancel = context.WithTimeout(ctx, m.Timeout)
+	}
+	defer cancel()
+	job, err := m.Client.WaitForReport(waitCtx, st.QueueID, nil)
+	if err != nil {
+		switch {
+		case ctx.Err() != nil:
+			// Stopped: the submission is resumed after a restart
+		case errors.Is(err, context.DeadlineExceeded):
+			m.fail(e.URL, err, nil) // Still pending, waited for again next round
+		default:
+			m.fail(e.URL, err, func(st *URLState) { st.QueueID = "" })
+		}
+		return
+	}
+
+	report, err := m.Client.GetReport(ctx, job.ReportID)
+	if err != nil {
+		m.fail(e.URL, err, nil)
+		return
+	}
+
+	// The verdict is kept when the reputation can't be checked, so it doesn't show as a change
+	verdict := st.Verdict
+	if rep, err := m.Client.CheckReputation(ctx, e.URL); err != nil {
+		m.emit(Event{Type: EventError, URL: e.URL, ReportID: report.ID, Error: err.Error()})
+	} else {
+		verdict = rep.Verdict
+	}
+
+	alerts := report.Stats.AlertCount
+	finalURL := ioc.FullURL(report.Final.Url)
+
+	checked := Event{Type: EventChecked, URL: e.URL, ReportID: report.ID, PreviousReportID: st.ReportID, Verdict: verdict, Alerts: &alerts}
+	var changes []Event
+	if st.ReportID != "" && st.ReportID != report.ID {
+		if previous, err := m.Client.GetReport(ctx, st.ReportID); err == nil {
+			checked.Diff = diff.Compare(previous, report)
+		}
+
+		change := Event{URL: e.URL, ReportID: report.ID, PreviousReportID: st.ReportID, Verdict: verdict}
+		if verdict != st.Verdict {
+			change.Type, change.From, change.To = EventVerdictChanged, st.Verdict, verdict
+			changes = append(changes, change)
+		}
+		if finalURL != st.FinalURL {
+			change.Type, change.From, change.To = EventFinalURLChanged, st.FinalURL, finalURL
+			changes = append(changes, change)
+		}
+		if alerts != st.Alerts {
+			change.Type, change.From, change.To = EventAlertsChanged, st.Alerts, alerts
+			changes = append(changes, change)
+		}
+	}
+
+	m.update(e.URL, func(s *URLState) {
+		s.QueueID, s.LastError = "", ""
+		s.ReportID, s.Checked = report.ID, m.clock()
+		s.Verdict, s.FinalURL, s.Alerts = verdict, finalURL, alerts
+	})
+
+	m.emit(checked)
+	for _, c := range changes {
+		m.emit(c)
+	}
+}
+
+// update changes the state of a URL, and reports when it can't be saved
+func (m *Monitor) update(url string, fn func(st *URLState)) {
+	if err := m.Store.Update(url, fn); err != nil {
+		m.emit(Event{Type: EventError, URL: url, Error: "saving state: " + err.Error()})
+	}
+}
+
+// fail records an error for the URL, with an optional state change
+func (m *Monitor) fail(url string, err error, fn func(st *URLState)) {
+	m.update(url, func(st *URLState) {
+		st.LastError = err.Error()
+		if fn != nil {
+			fn(st)
+		}
+	})
+	m.emit(Event{Type: EventError, URL: url, Error: err.Error()})
+}
@@ -0,0 +1,170 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+// fakeClient completes every submission with the next report of the list
+type fakeClient struct {
+	reports   []*api.Report
+	verdicts  []string
+	submitted []api.SubmitJob
+	pending   bool // WaitForReport times out
+}
+
+func (c *fakeClient) Submit(ctx context.Context, job api.SubmitJob) (*api.QueuedJob, error) {
+	c.submitted = append(c.submitted, job)
+	return &api.QueuedJob{QueueID: fmt.Sprintf("q%d", len(c.submitted)), Status: api.StatusQueued}, nil
+}
+
+func (c *fakeClient) WaitForReport(ctx context.Context, queueID string, onStatus func(*api.QueuedJob)) (*api.QueuedJob, error) {
+	if c.pending {
+		return nil, context.DeadlineExceeded
+	}
+	var n int
+	fmt.Sscanf(queueID, "q%d", &n)
+	return &api.QueuedJob{QueueID: queueID, ReportID: c.reports[n-1].ID, Status: api.StatusDone}, nil
+}
+
+func (c *fakeClient) GetReport(ctx context.Context, id string) (*api.Report, error) {
+	for _, r := range c.reports {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return nil, errors.New("not found")
+}
+
+func (c *fakeClient) CheckReputation(ctx context.Context, query string) (*api.ReputationResult, error) {
+	return &api.ReputationResult{Url: query, Verdict: c.verdicts[len(c.submitted)-1]}, nil
+}
+
+func report(id, final string, alerts int) *api.Report {
+	r := &api.Report{}
+	r.ID = id
+	r.Final.Url = api.URL{Schema: "https", Addr: final}
+	r.Stats.AlertCount.Urlquery = alerts
+	return r
+}
+
+func TestParseWatchlist(t *testing.T) {
+	w, err := ParseWatchlist([]byte(`
+defaults:
+  access: private
+  tags: [lookalike]
+urls:
+  - url: https://examp1e.com/login
+    interval: 6h
+    tags: [brand-x]
+  - url: https://example-login.net
+    interval: 2d
+    access: public
+`))
+	if err != nil {
+		t.Fatalf("ParseWatchlist() error = %v", err)
+	}
+
+	e := w.URLs[0]
+	if e.Interval != Interval(6*time.Hour) || e.Access != "private" || len(e.Tags) != 2 || e.Tags[1] != "brand-x" {
+		t.Errorf("Unexpected entry %+v", e)
+	}
+	if e := w.URLs[1]; e.Interval != Interval(48*time.Hour) || e.Access != "public" {
+		t.Errorf("Unexpected entry %+v", e)
+	}
+
+	for _, data := range []string{"urls: [{url: example.com}]", "urls: [{url: 'https://a.com', interval: 10s}]", "urls: [{link: 'https://a.com'}]"} {
+		if _, err := ParseWatchlist([]byte(data)); err == nil {
+			t.Errorf("Expected an error for %q", data)
+		}
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	client := &fakeClient{
+		reports: []*api.Report{
+			report("r1", "example.com/login", 0),
+			report("r2", "evil.example.org/", 3),
+		},
+		verdicts: []string{"clean", "malicious"},
+	}
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	store, _ := OpenStore(statePath)
+
+	now := time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC)
+	var events []Event
+	m := &Monitor{
+		Client:  client,
+		Store:   store,
+		Job:     api.SubmitJob{UserAgent: "test", Tags: []string{"monitor"}},
+		Workers: 2,
+		OnEvent: func(e Event) { events = append(events, e) },
+		now:     func() time.Time { return now },
+	}
+	w := &Watchlist{URLs: []Entry{{URL: "https://example.com", Interval: Interval(24 * time.Hour), Access: "private", Tags: []string{"brand"}}}}
+
+	m.RunOnce(context.Background(), w)
+	if len(client.submitted) != 1 || client.submitted[0].Access != "private" || len(client.submitted[0].Tags) != 2 {
+		t.Fatalf("Unexpected submissions %+v", client.submitted)
+	}
+	if len(events) != 2 || events[1].Type != EventChecked || events[1].Verdict != "clean" {
+		t.Fatalf("Unexpected events %+v", events)
+	}
+
+	// Not due before the interval
+	now = now.Add(time.Hour)
+	events = nil
+	m.RunOnce(context.Background(), w)
+	if len(client.submitted) != 1 || len(events) != 0 {
+		t.Fatalf("Unexpected submission before the interval")
+	}
+
+	// The state is read again after a restart, and the next scan is compared to r1
+	now = now.Add(24 * time.Hour)
+	m.Store, _ = OpenStore(statePath)
+	m.RunOnce(context.Background(), w)
+
+	types := []string{}
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	expected := []string{EventSubmitted, EventChecked, EventVerdictChanged, EventFinalURLChanged, EventAlertsChanged}
+	if fmt.Sprint(types) != fmt.Sprint(expected) {
+		t.Fatalf("Expected events %v, got %v", expected, types)
+	}
+	if d := events[1].Diff; d == nil || d.FinalURL == nil || events[1].PreviousReportID != "r1" {
+		t.Errorf("Expected a diff with r1, got %+v", events[1])
+	}
+	if events[2].From != "clean" || events[2].To != "malicious" {
+		t.Errorf("Unexpected verdict change %+v", events[2])
+	}
+	if st := m.Store.Get("https://example.com"); st.ReportID != "r2" || st.QueueID != "" || st.Alerts.Urlquery != 3 {
+		t.Errorf("Unexpected state %+v", st)
+	}
+}
+
+func TestMonitorResume(t *testing.T) {
+	client := &fakeClient{reports: []*api.Report{report("r1", "example.com/", 0)}, verdicts: []string{"clean"}, pending: true}
+	store, _ := OpenStore(filepath.Join(t.TempDir(), "state.json"))
+	m := &Monitor{Client: client, Store: store, Timeout: time.Second}
+	w := &Watchlist{URLs: []Entry{{URL: "https://example.com", Interval: Interval(time.Hour)}}}
+
+	// The report isn't ready yet: the submission stays pending
+	m.RunOnce(context.Background(), w)
+	if st := store.Get("https://example.com"); st.QueueID != "q1" || st.LastError == "" {
+		t.Fatalf("Unexpected state %+v", st)
+	}
+
+	// And is waited for again instead of submitted again
+	client.pending = false
+	m.RunOnce(context.Background(), w)
+	if st := store.Get("https://example.com"); len(client.submitted) != 1 || st.ReportID != "r1" || st.QueueID != "" {
+		t.Errorf("Unexpected state %+v after %d submissions", st, len(client.submitted))
+	}
+}
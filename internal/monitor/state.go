@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+// URLState is what is known about a watched URL: the pending submission, and the
+// last completed report
+type URLState struct {
+	URL string `json:"url"`
+
+	// Submission waiting for its report, resumed after a restart
+	QueueID   string    `json:"queue_id,omitempty"`
+	Submitted time.Time `json:"submitted,omitempty"`
+
+	// Last completed report
+	ReportID string          `json:"report_id,omitempty"`
+	Checked  time.Time       `json:"checked,omitempty"`
+	Verdict  string          `json:"verdict,omitempty"`
+	FinalURL string          `json:"final_url,omitempty"`
+	Alerts   api.AlertCounts `json:"alerts"`
+
+	LastError string `json:"last_error,omitempty"`
+}
+
+func (s URLState) Columns() []string {
+	return []string{"url", "report_id", "checked", "verdict", "final_url", "alerts_urlquery", "queue_id", "last_error"}
+}
+
+func (s URLState) Rows() [][]string {
+	checked := ""
+	if !s.Checked.IsZero() {
+		checked = s.Checked.Format(time.RFC3339)
+	}
+	return [][]string{{s.URL, s.ReportID, checked, s.Verdict, s.FinalURL, strconv.Itoa(s.Alerts.Urlquery), s.QueueID, s.LastError}}
+}
+
+// Store keeps the state of the watched URLs in a JSON file. It is saved after every
+// change, so a restarted monitor resumes where it stopped.
+type Store struct {
+	path string
+
+	mu   sync.Mutex
+	urls map[string]*URLState
+}
+
+// OpenStore reads the state file, or starts with an empty state if it doesn't exist
+func OpenStore(path string) (*Store, error) {
+	s := &Store{path: path, urls: make(map[string]*URLState)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.urls); err != nil {
+		return nil, fmt.Errorf("invalid monitor state %s: %w", path, err)
+	}
+	return s, nil
+}
+
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Get returns a copy of the state of a URL
+func (s *Store) Get(url string) URLState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if st, ok := s.urls[url]; ok {
+		return *st
+	}
+	return URLState{URL: url}
+}
+
+// Update changes the state of a URL and saves the state file
+func (s *Store) Update(url string, fn func(st *URLState)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, ok := s.urls[url]
+	if !ok {
+		st = &URLState{URL: url}
+		s.urls[url] = st
+	}
+	fn(st)
+	return s.save()
+}
+
+// List returns the state of every URL, by URL
+func (s *Store) List() []URLState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]URLState, 0, len(s.urls))
+	for _, st := range s.urls {
+		list = append(list, *st)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].URL < list[j].URL })
+	return list
+}
+
+// save writes the state file atomically. Called with the lock held.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s.urls, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".state-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,136 @@
+package monitor
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultInterval is the interval of watchlist entries without one
+const DefaultInterval = 24 * time.Hour
+
+// Watchlist is a list of URLs to scan periodically:
+//
+//	defaults:
+//	  interval: 24h
+//	  access: private
+//	  tags: [lookalike]
+//	urls:
+//	  - url: https://examp1e.com/login
+//	    interval: 6h
+//	    tags: [brand-x]
+type Watchlist struct {
+	Defaults Entry   `yaml:"defaults"`
+	URLs     []Entry `yaml:"urls"`
+}
+
+// Entry is a watched URL, and how it is submitted
+type Entry struct {
+	URL      string   `yaml:"url" json:"url"`
+	Interval Interval `yaml:"interval" json:"interval"`
+	Access   string   `yaml:"access" json:"access"`
+	Tags     []string `yaml:"tags" json:"tags"`
+}
+
+// Interval is a duration written as a Go duration (6h, 90m) or in days (1d, 7d)
+type Interval time.Duration
+
+func (i *Interval) UnmarshalYAML(unmarshal func(any) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	d, err := ParseInterval(s)
+	if err != nil {
+		return err
+	}
+	*i = Interval(d)
+	return nil
+}
+
+func (i Interval) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(i).String()), nil
+}
+
+// ParseInterval parses a Go duration, or a number of days (e.g. 7d)
+func ParseInterval(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid interval '%s'", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval '%s'", s)
+	}
+	return d, nil
+}
+
+// LoadWatchlist reads a watchlist file. The defaults are applied to every entry,
+// and the tags of the defaults are added to the tags of the entries.
+func LoadWatchlist(path string) (*Watchlist, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWatchlist(data)
+}
+
+// ParseWatchlist parses and validates a YAML watchlist
+func ParseWatchlist(data []byte) (*Watchlist, error) {
+	var w Watchlist
+	if err := yaml.UnmarshalStrict(data, &w); err != nil {
+		return nil, fmt.Errorf("invalid watchlist: %w", err)
+	}
+
+	if w.Defaults.Interval == 0 {
+		w.Defaults.Interval = Interval(DefaultInterval)
+	}
+
+	seen := make(map[string]bool)
+	for i := range w.URLs {
+		e := &w.URLs[i]
+		e.URL = strings.TrimSpace(e.URL)
+		u, err := url.Parse(e.URL)
+		if err != nil || u.Host == "" {
+			return nil, fmt.Errorf("invalid watchlist: invalid URL '%s'", e.URL)
+		}
+		if seen[e.URL] {
+			return nil, fmt.Errorf("invalid watchlist: duplicate URL '%s'", e.URL)
+		}
+		seen[e.URL] = true
+
+		if e.Interval == 0 {
+			e.Interval = w.Defaults.Interval
+		}
+		if e.Interval < Interval(time.Minute) {
+			return nil, fmt.Errorf("invalid watchlist: interval of '%s' is shorter than 1m", e.URL)
+		}
+		if e.Access == "" {
+			e.Access = w.Defaults.Access
+		}
+		e.Tags = mergeTags(w.Defaults.Tags, e.Tags)
+	}
+	return &w, nil
+}
+
+func mergeTags(lists ...[]string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		for _, t := range list {
+			if t = strings.TrimSpace(t); t != "" && !seen[t] {
+				seen[t] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	return tags
+}
@@ -0,0 +1,106 @@
+package notify
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Config is the notify section of the configuration file:
+//
+//	notify:
+//	  thresholds: {urlquery: 0, ids: 2}
+//	  verdicts: [malicious]
+//	  sinks:
+//	    - type: slack
+//	      url: https://hooks.slack.com/services/...
+//	    - type: webhook
+//	      url: https://soar.example.com/hooks/urlquery
+//	      headers: {Authorization: Bearer abc123}
+//	      template: '{"title": {{json .Title}}, "report": {{json .ReportURL}}}'
+//	    - type: smtp
+//	      addr: smtp.example.com:587
+//	      from: urlquery@example.com
+//	      to: [soc@example.com]
+//	      username: urlquery
+//	      password: secret
+type Config struct {
+	Thresholds map[string]int `mapstructure:"thresholds"`
+	Verdicts   []string       `mapstructure:"verdicts"`
+	Sinks      []SinkConfig   `mapstructure:"sinks"`
+
+	// Transport of the webhooks, e.g. with the proxy and CA of the API client (not
+	// in the configuration file; the default transport when nil)
+	Transport http.RoundTripper `mapstructure:"-"`
+}
+
+// SinkConfig configures a sink. The fields used depend on the type.
+type SinkConfig struct {
+	Type string `mapstructure:"type"` // webhook, slack, teams or smtp
+
+	// Webhooks
+	URL      string            `mapstructure:"url"`
+	Headers  map[string]string `mapstructure:"headers"`
+	Template string            `mapstructure:"template"`
+
+	// SMTP
+	Addr     string   `mapstructure:"addr"`
+	From     string   `mapstructure:"from"`
+	To       []string `mapstructure:"to"`
+	Username string   `mapstructure:"username"`
+	Password string   `mapstructure:"password"`
+}
+
+// New returns a notifier from the configuration. Rules which aren't configured are
+// the DefaultRules.
+func New(cfg Config) (*Notifier, error) {
+	nt := &Notifier{Rules: DefaultRules}
+	if cfg.Thresholds != nil {
+		for counter := range cfg.Thresholds {
+			switch counter {
+			case CounterUrlquery, CounterIds, CounterAnalyzer:
+			default:
+				return nil, fmt.Errorf("unknown alert counter '%s' in thresholds (available: %s, %s, %s)",
+					counter, CounterUrlquery, CounterIds, CounterAnalyzer)
+			}
+		}
+		nt.Rules.Thresholds = cfg.Thresholds
+	}
+	if cfg.Verdicts != nil {
+		nt.Rules.Verdicts = cfg.Verdicts
+	}
+
+	for i, sc := range cfg.Sinks {
+		sink, err := newSink(sc, cfg.Transport)
+		if err != nil {
+			return nil, fmt.Errorf("sink %d: %w", i+1, err)
+		}
+		nt.Sinks = append(nt.Sinks, sink)
+	}
+	return nt, nil
+}
+
+func newSink(sc SinkConfig, transport http.RoundTripper) (Sink, error) {
+	switch strings.ToLower(sc.Type) {
+	case "webhook", "slack", "teams":
+		if sc.URL == "" {
+			return nil, fmt.Errorf("%s sink without url", sc.Type)
+		}
+
+		payload := map[string]PayloadFunc{"webhook": JSONPayload, "slack": SlackPayload, "teams": TeamsPayload}[strings.ToLower(sc.Type)]
+		if sc.Template != "" {
+			var err error
+			if payload, err = TemplatePayload(sc.Template); err != nil {
+				return nil, err
+			}
+		}
+		return NewWebhook(strings.ToLower(sc.Type)+" "+sc.URL, sc.URL, sc.Headers, payload, transport), nil
+
+	case "smtp":
+		if sc.Addr == "" || sc.From == "" || len(sc.To) == 0 {
+			return nil, fmt.Errorf("smtp sink needs addr, from and to")
+		}
+		return &SMTP{Addr: sc.Addr, From: sc.From, To: sc.To, Username: sc.Username, Password: sc.Password}, nil
+	}
+	return nil, fmt.Errorf("unknown sink type '%s' (available: webhook, slack, teams, smtp)", sc.Type)
+}
@@ -0,0 +1,164 @@
+// Package notify sends notifications when a report has detections: when its alert
+// counts exceed the configured thresholds, or the reputation verdict of the URL is
+// one of the configured verdicts (malicious by default). Notifications are sent to
+// sinks: HTTP webhooks (with a templated JSON body, or Slack and Teams payloads) and
+// email over SMTP.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+// Alert counters which can have a threshold
+const (
+	CounterUrlquery = "urlquery"
+	CounterIds      = "ids"
+	CounterAnalyzer = "analyzer"
+)
+
+// alertCount returns the count of an alert counter
+func alertCount(c api.AlertCounts, counter string) int {
+	switch counter {
+	case CounterUrlquery:
+		return c.Urlquery
+	case CounterIds:
+		return c.Ids
+	case CounterAnalyzer:
+		return c.Analyzer
+	}
+	return 0
+}
+
+// Notification is sent for a report with detections
+type Notification struct {
+	Time      time.Time       `json:"time"`
+	Source    string          `json:"source"` // Command which found the detections (submit, monitor)
+	URL       string          `json:"url"`
+	ReportID  string          `json:"report_id"`
+	ReportURL string          `json:"report_url"`
+	Verdict   string          `json:"verdict,omitempty"`
+	Alerts    api.AlertCounts `json:"alerts"`
+	Tags      []string        `json:"tags,omitempty"`
+
+	// Why the notification is sent, set by the notifier
+	Reasons []string `json:"reasons"`
+}
+
+// FromReport returns the notification of a report, with the reputation verdict of its URL
+func FromReport(source string, r *api.Report, verdict string) Notification {
+	return Notification{
+		Time:      time.Now().UTC(),
+		Source:    source,
+		URL:       r.Url.Addr,
+		ReportID:  r.ID,
+		ReportURL: ReportURL(r.ID),
+		Verdict:   verdict,
+		Alerts:    r.Stats.AlertCount,
+		Tags:      r.Tags,
+	}
+}
+
+// ReportURL returns the link to a report on urlquery.net
+func ReportURL(reportID string) string {
+	return "https://urlquery.net/report/" + reportID
+}
+
+// Title is a one line description of the notification
+func (n Notification) Title() string {
+	return "urlquery: detections for " + n.URL
+}
+
+// Text is a plain text description of the notification
+func (n Notification) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "URL:     %s\n", n.URL)
+	fmt.Fprintf(&b, "Report:  %s\n", n.ReportURL)
+	if n.Verdict != "" {
+		fmt.Fprintf(&b, "Verdict: %s\n", n.Verdict)
+	}
+	fmt.Fprintf(&b, "Alerts:  urlquery %d, IDS %d, analyzer %d\n", n.Alerts.Urlquery, n.Alerts.Ids, n.Alerts.Analyzer)
+	if len(n.Tags) > 0 {
+		fmt.Fprintf(&b, "Tags:    %s\n", strings.Join(n.Tags, " "))
+	}
+	fmt.Fprintf(&b, "Reason:  %s\n", strings.Join(n.Reasons, ", "))
+	return b.String()
+}
+
+// Rules decide when a notification is sent
+type Rules struct {
+	// Notify when an alert count is above its threshold, by counter name
+	Thresholds map[string]int
+
+	// Notify when the reputation verdict is one of these
+	Verdicts []string
+}
+
+// DefaultRules notify for any urlquery alert, and for malicious URLs
+var DefaultRules = Rules{
+	Thresholds: map[string]int{CounterUrlquery: 0},
+	Verdicts:   []string{"malicious"},
+}
+
+// Reasons returns why the notification must be sent, or nothing if it must not
+func (r Rules) Reasons(n Notification) []string {
+	var reasons []string
+
+	counters := make([]string, 0, len(r.Thresholds))
+	for counter := range r.Thresholds {
+		counters = append(counters, counter)
+	}
+	sort.Strings(counters)
+	for _, counter := range counters {
+		if count := alertCount(n.Alerts, counter); count > r.Thresholds[counter] {
+			reasons = append(reasons, fmt.Sprintf("%d %s alerts (threshold %d)", count, counter, r.Thresholds[counter]))
+		}
+	}
+
+	if n.Verdict != "" && slices.ContainsFunc(r.Verdicts, func(v string) bool { return strings.EqualFold(v, n.Verdict) }) {
+		reasons = append(reasons, "verdict "+n.Verdict)
+	}
+	return reasons
+}
+
+// NeedsVerdict reports if the rules use the reputation verdict, which must then be
+// checked before notifying
+func (r Rules) NeedsVerdict() bool {
+	return len(r.Verdicts) > 0
+}
+
+// Sink is a destination of notifications
+type Sink interface {
+	Name() string
+	Send(ctx context.Context, n Notification) error
+}
+
+// Notifier sends notifications matching its rules to all of its sinks
+type Notifier struct {
+	Rules Rules
+	Sinks []Sink
+}
+
+// Notify sends the notification if it matches the rules, and reports if it was sent.
+// A failing sink doesn't prevent sending to the other sinks.
+func (nt *Notifier) Notify(ctx context.Context, n Notification) (bool, error) {
+	n.Reasons = nt.Rules.Reasons(n)
+	if len(n.Reasons) == 0 {
+		return false, nil
+	}
+
+	var errs []error
+	for _, s := range nt.Sinks {
+		if err := s.Send(ctx, n); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name(), err))
+		}
+	}
+	return true, errors.Join(errs...)
+}
@@ -0,0 +1,212 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+)
+
+func testNotification() Notification {
+	return Notification{
+		Time:      time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC),
+		Source:    "monitor",
+		URL:       "examp1e.com/login",
+		ReportID:  "r1",
+		ReportURL: ReportURL("r1"),
+		Verdict:   "malicious",
+		Alerts:    api.AlertCounts{Urlquery: 2, Ids: 1},
+	}
+}
+
+func TestRules(t *testing.T) {
+	n := testNotification()
+	if reasons := DefaultRules.Reasons(n); len(reasons) != 2 || reasons[1] != "verdict malicious" {
+		t.Errorf("Unexpected reasons %v", reasons)
+	}
+
+	rules := Rules{Thresholds: map[string]int{CounterUrlquery: 5, CounterIds: 1}}
+	if reasons := rules.Reasons(n); len(reasons) != 0 {
+		t.Errorf("Expected no reasons, got %v", reasons)
+	}
+
+	n.Alerts.Ids = 2
+	if reasons := rules.Reasons(n); len(reasons) != 1 || reasons[0] != "2 ids alerts (threshold 1)" {
+		t.Errorf("Unexpected reasons %v", reasons)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	var bodies []map[string]any
+	var auth []string
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	nt, err := New(Config{Sinks: []SinkConfig{
+		{Type: "webhook", URL: server.URL, Headers: map[string]string{"Authorization": "Bearer abc"},
+			Template: `{"title": {{json .Title}}, "reasons": {{json .Reasons}}}`},
+		{Type: "slack", URL: server.URL},
+		{Type: "teams", URL: server.URL},
+	}})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	sent, err := nt.Notify(context.Background(), testNotification())
+	if !sent || err != nil || len(bodies) != 3 {
+		t.Fatalf("Notify() = %v, %v (%d requests)", sent, err, len(bodies))
+	}
+	if bodies[0]["title"] != "urlquery: detections for examp1e.com/login" || auth[0] != "Bearer abc" {
+		t.Errorf("Unexpected webhook body %v", bodies[0])
+	}
+	if !strings.Contains(bodies[1]["text"].(string), "verdict malicious") {
+		t.Errorf("Unexpected slack body %v", bodies[1])
+	}
+	if bodies[2]["@type"] != "MessageCard" {
+		t.Errorf("Unexpected teams body %v", bodies[2])
+	}
+
+	// No notification below the thresholds
+	n := testNotification()
+	n.Verdict, n.Alerts = "clean", api.AlertCounts{}
+	if sent, _ := nt.Notify(context.Background(), n); sent || len(bodies) != 3 {
+		t.Errorf("Unexpected notification")
+	}
+
+	status = http.StatusInternalServerError
+	if _, err := nt.Notify(context.Background(), testNotification()); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Expected an error, got %v", err)
+	}
+}
+
+// transportFunc records the requests sent through a transport
+type transportFunc func(*http.Request) (*http.Response, error)
+
+func (f transportFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestWebhookTransport(t *testing.T) {
+	var hosts []string
+	transport := transportFunc(func(r *http.Request) (*http.Response, error) {
+		hosts = append(hosts, r.URL.Host)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Request: r}, nil
+	})
+
+	nt, err := New(Config{Sinks: []SinkConfig{{Type: "slack", URL: "https://hooks.example.com/x"}}, Transport: transport})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := nt.Notify(context.Background(), testNotification()); err != nil || len(hosts) != 1 || hosts[0] != "hooks.example.com" {
+		t.Errorf("Notify() error = %v, requests to %v", err, hosts)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	configs := []Config{
+		{Thresholds: map[string]int{"total": 1}},
+		{Sinks: []SinkConfig{{Type: "pager"}}},
+		{Sinks: []SinkConfig{{Type: "slack"}}},
+		{Sinks: []SinkConfig{{Type: "smtp", Addr: "localhost:25"}}},
+		{Sinks: []SinkConfig{{Type: "webhook", URL: "http://x", Template: "{{.Missing"}}},
+	}
+	for _, cfg := range configs {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("Expected an error for %+v", cfg)
+		}
+	}
+}
+
+// smtpServer is a minimal SMTP server accepting one message
+func smtpServer(t *testing.T) (string, chan string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		reply("220 localhost ESMTP")
+
+		var envelope []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"):
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case strings.HasPrefix(cmd, "AUTH"), strings.HasPrefix(cmd, "MAIL"), strings.HasPrefix(cmd, "RCPT"):
+				envelope = append(envelope, strings.TrimSpace(line))
+				if strings.HasPrefix(cmd, "AUTH") {
+					reply("235 OK")
+				} else {
+					reply("250 OK")
+				}
+			case cmd == "DATA":
+				reply("354 Go ahead")
+				var data strings.Builder
+				for {
+					l, _ := r.ReadString('\n')
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				reply("250 Queued")
+				messages <- strings.Join(envelope, "\n") + "\n\n" + data.String()
+			case cmd == "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return l.Addr().String(), messages
+}
+
+func TestSMTP(t *testing.T) {
+	addr, messages := smtpServer(t)
+
+	s := &SMTP{Addr: addr, From: "urlquery@example.com", To: []string{"soc@example.com"}, Username: "u", Password: "p"}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Send(ctx, testNotification()); err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+
+	msg := <-messages
+	for _, expected := range []string{"AUTH PLAIN", "RCPT TO:<soc@example.com>", "Subject: urlquery: detections for examp1e.com/login", "Report:  https://urlquery.net/report/r1"} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("Message doesn't contain %q:\n%s", expected, msg)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// PayloadFunc returns the JSON body posted to a webhook
+type PayloadFunc func(n Notification) ([]byte, error)
+
+// Webhook posts notifications as JSON to a URL
+type Webhook struct {
+	name    string
+	url     string
+	headers map[string]string
+	payload PayloadFunc
+	client  *http.Client
+}
+
+// NewWebhook returns a webhook posting the payload to the URL, with extra headers.
+// Requests are sent with the transport, or the default transport when nil.
+func NewWebhook(name, url string, headers map[string]string, payload PayloadFunc, transport http.RoundTripper) *Webhook {
+	return &Webhook{
+		name:    name,
+		url:     url,
+		headers: headers,
+		payload: payload,
+		client:  &http.Client{Timeout: 30 * time.Second, Transport: transport},
+	}
+}
+
+func (w *Webhook) Name() string {
+	return w.name
+}
+
+func (w *Webhook) Send(ctx context.Context, n Notification) error {
+	body, err := w.payload(n)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "urlquery-cli")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// JSONPayload posts the notification as is
+func JSONPayload(n Notification) ([]byte, error) {
+	return json.Marshal(n)
+}
+
+// TemplatePayload posts the output of a Go template executed with the notification.
+// The json function writes a value as JSON, e.g. {"text": {{json .Title}}}. The output
+// must be valid JSON.
+func TemplatePayload(text string) (PayloadFunc, error) {
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"join": strings.Join,
+	}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid webhook template: %w", err)
+	}
+
+	return func(n Notification) ([]byte, error) {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, n); err != nil {
+			return nil, err
+		}
+		if !json.Valid(buf.Bytes()) {
+			return nil, fmt.Errorf("webhook template output is not valid JSON: %s", buf.String())
+		}
+		return buf.Bytes(), nil
+	}, nil
+}
+
+// SlackPayload posts a Slack incoming webhook message (also accepted by Mattermost
+// and Rocket.Chat)
+func SlackPayload(n Notification) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"text": fmt.Sprintf("*%s*\n```%s```", n.Title(), n.Text()),
+	})
+}
+
+// TeamsPayload posts a Microsoft Teams message card
+func TeamsPayload(n Notification) ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"@type":      "MessageCard",
+		"@context":   "https://schema.org/extensions",
+		"summary":    n.Title(),
+		"title":      n.Title(),
+		"themeColor": "D73A49",
+		"text":       strings.ReplaceAll(strings.TrimSpace(n.Text()), "\n", "<br>"),
+		"potentialAction": []map[string]any{{
+			"@type":   "OpenUri",
+			"name":    "Open report",
+			"targets": []map[string]string{{"os": "default", "uri": n.ReportURL}},
+		}},
+	})
+}
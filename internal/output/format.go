@@ -0,0 +1,325 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Formatter writes a value in a specific output format
+type Formatter interface {
+	Format(w io.Writer, v any) error
+}
+
+// StreamFormatter is implemented by formatters which can write the items of a
+// stream one at a time, instead of buffering them until the stream is closed.
+type StreamFormatter interface {
+	Formatter
+	FormatItem(w io.Writer, v any, first bool) error
+}
+
+// Tabular is implemented by values which can be written as rows (csv and table formats)
+type Tabular interface {
+	Columns() []string
+	Rows() [][]string
+}
+
+// Lister is implemented by values holding a list of items, which are written as
+// one line each in the ndjson format (e.g. the reports of a search response).
+type Lister interface {
+	Items() []any
+}
+
+// FormatterFunc creates a formatter. arg is the value following '=' in the format
+// specification (e.g. the file in "template=report.tmpl"), or empty.
+type FormatterFunc func(arg string) (Formatter, error)
+
+var formatters = map[string]FormatterFunc{}
+
+// Register adds a named formatter to the registry, replacing any existing one
+func Register(name string, fn FormatterFunc) {
+	formatters[name] = fn
+}
+
+// Names returns the names of the registered formatters
+func Names() []string {
+	names := make([]string, 0, len(formatters))
+	for name := range formatters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New returns the formatter for a format specification such as "csv" or "template=<file>"
+func New(spec string) (Formatter, error) {
+	name, arg, _ := strings.Cut(spec, "=")
+
+	fn, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format '%s' (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return fn(arg)
+}
+
+func init() {
+	Register("json", func(string) (Formatter, error) { return jsonFormatter{}, nil })
+	Register("ndjson", func(string) (Formatter, error) { return ndjsonFormatter{}, nil })
+	Register("yaml", func(string) (Formatter, error) { return yamlFormatter{}, nil })
+	Register("csv", func(string) (Formatter, error) { return csvFormatter{}, nil })
+	Register("table", func(string) (Formatter, error) { return tableFormatter{}, nil })
+	Register("template", newTemplateFormatter)
+}
+
+// --- json ---
+
+type jsonFormatter struct{}
+
+func (jsonFormatter) Format(w io.Writer, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to format JSON: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// --- ndjson ---
+
+type ndjsonFormatter struct{}
+
+func (f ndjsonFormatter) Format(w io.Writer, v any) error {
+	if l, ok := v.(Lister); ok {
+		for i, item := range l.Items() {
+			if err := f.FormatItem(w, item, i == 0); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return f.FormatItem(w, v, true)
+}
+
+func (ndjsonFormatter) FormatItem(w io.Writer, v any, first bool) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// --- yaml ---
+
+type yamlFormatter struct{}
+
+// Format writes v as YAML, using the JSON field names and order
+func (yamlFormatter) Format(w io.Writer, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to format YAML: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	value, err := decodeOrdered(dec)
+	if err != nil {
+		return fmt.Errorf("failed to format YAML: %w", err)
+	}
+
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to format YAML: %w", err)
+	}
+	_, err = w.Write(out)
+	return err
+}
+
+// decodeOrdered decodes the next JSON value, keeping the order of object keys
+func decodeOrdered(dec *json.Decoder) (any, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := yaml.MapSlice{}
+			for dec.More() {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				value, err := decodeOrdered(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj = append(obj, yaml.MapItem{Key: key, Value: value})
+			}
+			_, err = dec.Token() // '}'
+			return obj, err
+
+		case '[':
+			arr := []any{}
+			for dec.More() {
+				value, err := decodeOrdered(dec)
+				if err != nil {
+					return nil, err
+				}
+				arr = append(arr, value)
+			}
+			_, err = dec.Token() // ']'
+			return arr, err
+		}
+
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i, nil
+		}
+		return t.Float64()
+	}
+
+	return tok, nil
+}
+
+// --- csv ---
+
+type csvFormatter struct{}
+
+func (f csvFormatter) Format(w io.Writer, v any) error {
+	return f.FormatItem(w, v, true)
+}
+
+func (csvFormatter) FormatItem(w io.Writer, v any, first bool) error {
+	t, ok := v.(Tabular)
+	if !ok {
+		return fmt.Errorf("csv output is not supported for %T", v)
+	}
+
+	cw := csv.NewWriter(w)
+	if first {
+		cw.Write(t.Columns())
+	}
+	cw.WriteAll(t.Rows())
+	return cw.Error()
+}
+
+// --- table ---
+
+type tableFormatter struct{}
+
+func (tableFormatter) Format(w io.Writer, v any) error {
+	t, ok := v.(Tabular)
+	if !ok {
+		return fmt.Errorf("table output is not supported for %T", v)
+	}
+
+	FprintTable(w, t.Columns(), t.Rows())
+	return nil
+}
+
+// --- template ---
+
+// TemplateFuncs are the functions available in templates used with the template format
+var TemplateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(v any) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+type templateFormatter struct {
+	tmpl *template.Template
+}
+
+func newTemplateFormatter(file string) (Formatter, error) {
+	if file == "" {
+		return nil, fmt.Errorf("template format requires a file: --format template=<file>")
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	tmpl, err := template.New(file).Funcs(TemplateFuncs).Parse(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	return templateFormatter{tmpl: tmpl}, nil
+}
+
+func (f templateFormatter) Format(w io.Writer, v any) error {
+	return f.tmpl.Execute(w, v)
+}
+
+func (f templateFormatter) FormatItem(w io.Writer, v any, first bool) error {
+	return f.tmpl.Execute(w, v)
+}
+
+// --- streams ---
+
+// Stream writes a sequence of items. Items are written as they arrive when the
+// formatter supports it (ndjson, csv, template), otherwise they are buffered and
+// written as a list when the stream is closed (json, yaml, table).
+type Stream struct {
+	w     io.Writer
+	f     Formatter
+	items List
+	count int
+}
+
+func NewStream(w io.Writer, f Formatter) *Stream {
+	return &Stream{w: w, f: f, items: List{}}
+}
+
+// Write adds an item to the stream
+func (s *Stream) Write(v any) error {
+	s.count++
+	if sf, ok := s.f.(StreamFormatter); ok {
+		return sf.FormatItem(s.w, v, s.count == 1)
+	}
+
+	s.items = append(s.items, v)
+	return nil
+}
+
+// Close writes the buffered items, if any
+func (s *Stream) Close() error {
+	if _, ok := s.f.(StreamFormatter); ok {
+		return nil
+	}
+	return s.f.Format(s.w, s.items)
+}
+
+// List is a list of items, which are written as rows when the items are Tabular
+type List []any
+
+func (l List) Items() []any {
+	return l
+}
+
+func (l List) Columns() []string {
+	for _, item := range l {
+		if t, ok := item.(Tabular); ok {
+			return t.Columns()
+		}
+	}
+	return nil
+}
+
+func (l List) Rows() [][]string {
+	var rows [][]string
+	for _, item := range l {
+		if t, ok := item.(Tabular); ok {
+			rows = append(rows, t.Rows()...)
+		}
+	}
+	return rows
+}
@@ -0,0 +1,124 @@
+package output
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+func (i testItem) Columns() []string { return []string{"name", "count"} }
+func (i testItem) Rows() [][]string  { return [][]string{{i.Name, "n"}} }
+
+func format(t *testing.T, spec string, v any) string {
+	t.Helper()
+	f, err := New(spec)
+	if err != nil {
+		t.Fatalf("New(%q) error = %v", spec, err)
+	}
+
+	var buf bytes.Buffer
+	if err := f.Format(&buf, v); err != nil {
+		t.Fatalf("Format() error = %v", err)
+	}
+	return buf.String()
+}
+
+func TestFormats(t *testing.T) {
+	item := testItem{Name: "a", Count: 2}
+
+	tests := []struct {
+		spec string
+		v    any
+		want string
+	}{
+		{"json", item, "{\n  \"name\": \"a\",\n  \"count\": 2\n}\n"},
+		{"ndjson", item, "{\"name\":\"a\",\"count\":2}\n"},
+		{"ndjson", List{item, item}, "{\"name\":\"a\",\"count\":2}\n{\"name\":\"a\",\"count\":2}\n"},
+		{"yaml", item, "name: a\ncount: 2\n"},
+		{"csv", item, "name,count\na,n\n"},
+		{"csv", List{item, item}, "name,count\na,n\na,n\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			if got := format(t, tt.spec, tt.v); got != tt.want {
+				t.Errorf("Format() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateFormat(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "item.tmpl")
+	os.WriteFile(file, []byte(`{{.Name}}={{.Count}}{{"\n"}}`), 0644)
+
+	if got := format(t, "template="+file, testItem{Name: "a", Count: 2}); got != "a=2\n" {
+		t.Errorf("Format() = %q", got)
+	}
+
+	if _, err := New("template"); err == nil {
+		t.Error("Expected error for template without file")
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := New("xml"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestUnsupportedTabular(t *testing.T) {
+	f, _ := New("csv")
+	if err := f.Format(&bytes.Buffer{}, map[string]string{}); err == nil {
+		t.Error("Expected error for non tabular value")
+	}
+}
+
+func TestStream(t *testing.T) {
+	item := testItem{Name: "a", Count: 2}
+
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"ndjson", "{\"name\":\"a\",\"count\":2}\n{\"name\":\"a\",\"count\":2}\n"},
+		{"csv", "name,count\na,n\na,n\n"},
+		{"json", "[\n  {\n    \"name\": \"a\",\n    \"count\": 2\n  },\n  {\n    \"name\": \"a\",\n    \"count\": 2\n  }\n]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			f, _ := New(tt.spec)
+			var buf bytes.Buffer
+
+			s := NewStream(&buf, f)
+			s.Write(item)
+			s.Write(item)
+			if err := s.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			if buf.String() != tt.want {
+				t.Errorf("Stream = %q, want %q", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyJSONStream(t *testing.T) {
+	f, _ := New("json")
+	var buf bytes.Buffer
+
+	if err := NewStream(&buf, f).Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if buf.String() != "[]\n" {
+		t.Errorf("Stream = %q, want []", buf.String())
+	}
+}
@@ -3,6 +3,8 @@ package output
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"strings"
 
 	"github.com/fatih/color"
@@ -57,6 +59,11 @@ func PrintKeyValue(key, value string) {
 
 // PrintTable prints data in a simple table format
 func PrintTable(headers []string, rows [][]string) {
+	FprintTable(os.Stdout, headers, rows)
+}
+
+// FprintTable writes data in a simple table format to w
+func FprintTable(w io.Writer, headers []string, rows [][]string) {
 	if len(headers) == 0 || len(rows) == 0 {
 		return
 	}
@@ -78,23 +85,23 @@ func PrintTable(headers []string, rows [][]string) {
 	// Print header
 	bold := color.New(color.Bold).SprintFunc()
 	for i, header := range headers {
-		fmt.Printf("%-*s", widths[i]+2, bold(header))
+		fmt.Fprint(w, bold(fmt.Sprintf("%-*s", widths[i]+2, header)))
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 
 	// Print separator
 	for _, width := range widths {
-		fmt.Print(strings.Repeat("─", width+2))
+		fmt.Fprint(w, strings.Repeat("─", width+2))
 	}
-	fmt.Println()
+	fmt.Fprintln(w)
 
 	// Print rows
 	for _, row := range rows {
 		for i, cell := range row {
 			if i < len(widths) {
-				fmt.Printf("%-*s", widths[i]+2, cell)
+				fmt.Fprintf(w, "%-*s", widths[i]+2, cell)
 			}
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
 }
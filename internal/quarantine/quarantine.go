@@ -0,0 +1,309 @@
+// Package quarantine stores potentially malicious files so they can't be opened
+// by accident: packed in a zip encrypted with the conventional "infected" password,
+// or renamed with a defanged extension, read-only and never executable. A sidecar
+// JSON file describes where the file comes from and what the analyzers found.
+package quarantine
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/integrity"
+	"github.com/urlquery/urlquery-cli/internal/ioc"
+)
+
+// Password of the quarantine zip files, by the convention used for malware samples
+const Password = "infected"
+
+// Quarantine modes
+const (
+	ModeZip    = "zip"
+	ModeDefang = "defang"
+)
+
+// Permissions of quarantined files: read-only, and never executable
+const FileMode os.FileMode = 0400
+
+// Verdict is an analyzer result for the file
+type Verdict struct {
+	Sensor   string `json:"sensor"`
+	Alert    string `json:"alert,omitempty"`
+	Verdict  string `json:"verdict,omitempty"`
+	Severity string `json:"severity,omitempty"`
+}
+
+// Sidecar describes a quarantined file. It is written next to it as <file>.json.
+type Sidecar struct {
+	File       string   `json:"file"`
+	Mode       string   `json:"quarantine"`
+	Password   string   `json:"password,omitempty"`
+	ReportID   string   `json:"report_id"`
+	OriginURLs []string `json:"origin_urls"`
+	MimeType   string   `json:"mime_type,omitempty"`
+	Magic      string   `json:"magic,omitempty"`
+	Size       int64    `json:"size"`
+	integrity.Digest
+	Verdicts []Verdict `json:"verdicts"`
+	Created  string    `json:"created"`
+}
+
+// NewSidecar describes a downloaded resource, with the URLs it was served from and the
+// analyzer verdicts from the report. report may be nil when it couldn't be fetched.
+func NewSidecar(reportID string, report *api.Report, d integrity.Digest, size int64) *Sidecar {
+	s := &Sidecar{
+		ReportID:   reportID,
+		OriginURLs: []string{},
+		Size:       size,
+		Digest:     d,
+		Verdicts:   []Verdict{},
+		Created:    time.Now().UTC().Format(time.RFC3339),
+	}
+	if report == nil {
+		return s
+	}
+
+	matches := func(sha256 string) bool {
+		return sha256 != "" && strings.EqualFold(sha256, d.SHA256)
+	}
+	addURL := func(u api.URL) {
+		if full := ioc.FullURL(u); full != "" && !contains(s.OriginURLs, full) {
+			s.OriginURLs = append(s.OriginURLs, full)
+		}
+	}
+	addVerdicts := func(alerts []api.AnalyzerAlert) {
+		for _, a := range alerts {
+			v := Verdict{Sensor: a.SensorName, Alert: a.Alert, Verdict: a.Verdict, Severity: a.Severity}
+			if !containsVerdict(s.Verdicts, v) {
+				s.Verdicts = append(s.Verdicts, v)
+			}
+		}
+	}
+
+	for _, tx := range report.HttpTransactions {
+		c := tx.Response.Content
+		if !matches(c.Sha256) {
+			continue
+		}
+		addURL(tx.Url)
+		addVerdicts(tx.Alerts.AnalyzerAlerts)
+		if s.MimeType == "" {
+			s.MimeType = c.MimeType
+		}
+		if s.Magic == "" {
+			s.Magic = c.Magic
+		}
+	}
+	for _, f := range report.FileDetections {
+		if !matches(f.Sha256) {
+			continue
+		}
+		addURL(f.Url)
+		addVerdicts(f.Alerts.AnalyzerAlerts)
+		if s.Magic == "" {
+			s.Magic = f.Magic
+		}
+	}
+	return s
+}
+
+// Apply quarantines the file src as path with the given mode, and writes its sidecar.
+// src (e.g. a partial download) is removed, and is never renamed to path in clear.
+// Returns the path of the quarantined file.
+func Apply(src, path, mode string, s *Sidecar) (string, error) {
+	var dst string
+	switch mode {
+	case ModeZip:
+		dst = path + ".zip"
+		if err := zipFile(src, filepath.Base(path), dst); err != nil {
+			return "", err
+		}
+		if err := os.Remove(src); err != nil {
+			return "", err
+		}
+		s.Password = Password
+
+	case ModeDefang:
+		dst = DefangedName(path, s.MimeType)
+		if err := os.Rename(src, dst); err != nil {
+			return "", err
+		}
+		if err := os.Chmod(dst, FileMode); err != nil {
+			return "", err
+		}
+
+	default:
+		return "", fmt.Errorf("unknown quarantine mode '%s' (available: %s, %s)", mode, ModeZip, ModeDefang)
+	}
+
+	s.File = filepath.Base(dst)
+	s.Mode = mode
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return dst, os.WriteFile(dst+".json", data, 0644)
+}
+
+// Extensions of common MIME types, used to name defanged files
+var extensions = map[string]string{
+	"application/x-dosexec":                         "exe",
+	"application/x-msdownload":                      "exe",
+	"application/vnd.microsoft.portable-executable": "exe",
+	"application/x-msi":                             "msi",
+	"application/java-archive":                      "jar",
+	"application/javascript":                        "js",
+	"text/javascript":                               "js",
+	"application/pdf":                               "pdf",
+	"application/zip":                               "zip",
+	"application/x-rar-compressed":                  "rar",
+	"application/x-7z-compressed":                   "7z",
+	"application/msword":                            "doc",
+	"application/vnd.ms-excel":                      "xls",
+	"application/x-sh":                              "sh",
+	"application/x-shockwave-flash":                 "swf",
+	"application/vnd.android.package-archive":       "apk",
+	"text/html":                                     "html",
+	"image/svg+xml":                                 "svg",
+	"application/x-iso9660-image":                   "iso",
+}
+
+// DefangedName appends the extension of the MIME type followed by an underscore
+// (e.g. resource_<hash>.exe_), so the file isn't associated with an application
+func DefangedName(path, mimeType string) string {
+	mimeType, _, _ = strings.Cut(mimeType, ";")
+	ext, ok := extensions[strings.ToLower(strings.TrimSpace(mimeType))]
+	if !ok {
+		ext = "bin"
+	}
+	return path + "." + ext + "_"
+}
+
+// zipFile packs src as name into an encrypted zip file dst, created read-only
+func zipFile(src, name, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	// A read-only file left by a previous download can't be opened for writing
+	os.Remove(dst)
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, FileMode)
+	if err != nil {
+		return err
+	}
+
+	err = WriteZip(out, name, info.ModTime(), in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
+
+// WriteZip writes a zip archive to w with a single file, compressed and encrypted
+// with the traditional PKWARE encryption (ZipCrypto) and Password. ZipCrypto is weak,
+// but it is what malware sharing expects, and what every unzip tool can open.
+func WriteZip(w io.Writer, name string, modified time.Time, r io.Reader) error {
+	zw := zip.NewWriter(w)
+
+	fh := &zip.FileHeader{
+		Name:   name,
+		Method: zip.Deflate,
+		Flags:  0x1 | 0x8, // Encrypted, with the CRC and sizes in a data descriptor
+	}
+	fh.ModifiedDate, fh.ModifiedTime = msDosTime(modified)
+	fh.SetMode(FileMode)
+
+	raw, err := zw.CreateRaw(fh)
+	if err != nil {
+		return err
+	}
+
+	// With a data descriptor, the check byte of the encryption header is the high
+	// byte of the modification time instead of the CRC, which isn't known yet
+	enc := newZipCrypto(Password)
+	counter := &countingWriter{w: raw}
+	if _, err := counter.Write(enc.header(byte(fh.ModifiedTime >> 8))); err != nil {
+		return err
+	}
+
+	fw, err := flate.NewWriter(&cryptoWriter{w: counter, c: enc}, flate.DefaultCompression)
+	if err != nil {
+		return err
+	}
+
+	crc := crc32.NewIEEE()
+	n, err := io.Copy(io.MultiWriter(fw, crc), r)
+	if err != nil {
+		return err
+	}
+	if err := fw.Close(); err != nil {
+		return err
+	}
+
+	// The header is referenced by the zip writer until the archive is closed
+	fh.CRC32 = crc.Sum32()
+	fh.UncompressedSize64 = uint64(n)
+	fh.CompressedSize64 = uint64(counter.n)
+	fh.UncompressedSize = uint32(min(fh.UncompressedSize64, 0xffffffff))
+	fh.CompressedSize = uint32(min(fh.CompressedSize64, 0xffffffff))
+
+	return zw.Close()
+}
+
+// msDosTime converts a time to the MS-DOS date and time format of zip headers
+func msDosTime(t time.Time) (date uint16, tm uint16) {
+	if t.Year() < 1980 {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	date = uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9)
+	tm = uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)
+	return date, tm
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsVerdict(list []Verdict, value Verdict) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package quarantine
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/integrity"
+)
+
+func TestWriteZip(t *testing.T) {
+	content := strings.Repeat("MZ sample ", 500)
+
+	var buf bytes.Buffer
+	if err := WriteZip(&buf, "sample", time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC), strings.NewReader(content)); err != nil {
+		t.Fatalf("WriteZip() error = %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Invalid zip: %v", err)
+	}
+	f := zr.File[0]
+	if f.Name != "sample" || f.Flags&0x1 == 0 || f.UncompressedSize64 != uint64(len(content)) {
+		t.Fatalf("Unexpected entry %+v", f.FileHeader)
+	}
+
+	// Decrypt with the password, check the header and inflate
+	raw, err := f.OpenRaw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(raw)
+
+	z := newZipCrypto(Password)
+	z.decrypt(data)
+	if data[11] != byte(f.ModifiedTime>>8) {
+		t.Fatalf("Unexpected check byte %x", data[11])
+	}
+
+	plain, err := io.ReadAll(flate.NewReader(bytes.NewReader(data[12:])))
+	if err != nil || string(plain) != content {
+		t.Errorf("Decrypted content doesn't match (%v)", err)
+	}
+}
+
+func TestApply(t *testing.T) {
+	report := &api.Report{}
+	tx := api.HttpTransaction{Url: api.URL{Schema: "https", Addr: "example.com/setup.exe"}}
+	tx.Response.Content = api.HttpContent{MimeType: "application/x-dosexec", Magic: "PE32 executable", Sha256: "ABC"}
+	tx.Alerts.AnalyzerAlerts = []api.AnalyzerAlert{{SensorName: "yara", Alert: "Trojan", Verdict: "malicious"}}
+	report.HttpTransactions = []api.HttpTransaction{tx}
+
+	for _, mode := range []string{ModeZip, ModeDefang} {
+		t.Run(mode, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "resource_abc")
+			src := path + ".part"
+			os.WriteFile(src, []byte("MZ"), 0600)
+
+			sidecar := NewSidecar("r1", report, integrity.Digest{SHA256: "abc"}, 2)
+			dst, err := Apply(src, path, mode, sidecar)
+			if err != nil {
+				t.Fatalf("Apply() error = %v", err)
+			}
+
+			expected := map[string]string{ModeZip: path + ".zip", ModeDefang: path + ".exe_"}[mode]
+			if dst != expected {
+				t.Errorf("Expected %s, got %s", expected, dst)
+			}
+			if _, err := os.Stat(src); !os.IsNotExist(err) {
+				t.Errorf("Original file not removed")
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("File saved in clear as %s", path)
+			}
+			if mode == ModeZip {
+				zr, err := zip.OpenReader(dst)
+				if err != nil {
+					t.Fatalf("Invalid zip: %v", err)
+				}
+				if name := zr.File[0].Name; name != "resource_abc" {
+					t.Errorf("Unexpected entry %s", name)
+				}
+				zr.Close()
+			}
+			if info, err := os.Stat(dst); err != nil || info.Mode().Perm() != FileMode {
+				t.Errorf("Unexpected permissions %v (%v)", info.Mode(), err)
+			}
+
+			var s Sidecar
+			data, _ := os.ReadFile(dst + ".json")
+			if err := json.Unmarshal(data, &s); err != nil {
+				t.Fatalf("Invalid sidecar: %v", err)
+			}
+			if s.File != filepath.Base(dst) || s.Mode != mode || s.MimeType != "application/x-dosexec" {
+				t.Errorf("Unexpected sidecar %+v", s)
+			}
+			if len(s.OriginURLs) != 1 || s.OriginURLs[0] != "https://example.com/setup.exe" {
+				t.Errorf("Unexpected origin %v", s.OriginURLs)
+			}
+			if len(s.Verdicts) != 1 || s.Verdicts[0].Verdict != "malicious" {
+				t.Errorf("Unexpected verdicts %v", s.Verdicts)
+			}
+		})
+	}
+}
+
+func TestDefangedName(t *testing.T) {
+	if name := DefangedName("resource_abc", "text/javascript; charset=utf-8"); name != "resource_abc.js_" {
+		t.Errorf("Unexpected name %s", name)
+	}
+	if name := DefangedName("resource_abc", ""); name != "resource_abc.bin_" {
+		t.Errorf("Unexpected name %s", name)
+	}
+}
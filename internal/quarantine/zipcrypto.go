@@ -0,0 +1,72 @@
+package quarantine
+
+import (
+	"crypto/rand"
+	"hash/crc32"
+	"io"
+)
+
+// zipCrypto implements the traditional PKWARE encryption, as described in
+// section 6.1 of the zip specification (APPNOTE.TXT)
+type zipCrypto struct {
+	keys [3]uint32
+}
+
+func newZipCrypto(password string) *zipCrypto {
+	z := &zipCrypto{keys: [3]uint32{0x12345678, 0x23456789, 0x34567890}}
+	for i := 0; i < len(password); i++ {
+		z.update(password[i])
+	}
+	return z
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ (crc >> 8)
+}
+
+func (z *zipCrypto) update(b byte) {
+	z.keys[0] = crc32Update(z.keys[0], b)
+	z.keys[1] = (z.keys[1]+(z.keys[0]&0xff))*134775813 + 1
+	z.keys[2] = crc32Update(z.keys[2], byte(z.keys[1]>>24))
+}
+
+func (z *zipCrypto) stream() byte {
+	t := uint16(z.keys[2] | 2)
+	return byte((t * (t ^ 1)) >> 8)
+}
+
+func (z *zipCrypto) encrypt(p []byte) {
+	for i, b := range p {
+		p[i] = b ^ z.stream()
+		z.update(b)
+	}
+}
+
+func (z *zipCrypto) decrypt(p []byte) {
+	for i, c := range p {
+		p[i] = c ^ z.stream()
+		z.update(p[i])
+	}
+}
+
+// header returns the encrypted 12 byte encryption header: 11 random bytes and the check byte
+func (z *zipCrypto) header(check byte) []byte {
+	h := make([]byte, 12)
+	rand.Read(h[:11])
+	h[11] = check
+	z.encrypt(h)
+	return h
+}
+
+// cryptoWriter encrypts everything written to it
+type cryptoWriter struct {
+	w io.Writer
+	c *zipCrypto
+}
+
+func (cw *cryptoWriter) Write(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	copy(buf, p)
+	cw.c.encrypt(buf)
+	return cw.w.Write(buf)
+}
@@ -0,0 +1,203 @@
+// Package secrets stores secrets such as API keys in a file encrypted with a
+// passphrase. The key is derived from the passphrase with scrypt, and the secrets are
+// encrypted with AES-256-GCM. Config values refer to a secret as secret:<name>.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// RefPrefix prefixes config values referring to a secret
+const RefPrefix = "secret:"
+
+// Ref returns the name of the secret a config value refers to
+func Ref(value string) (string, bool) {
+	name, ok := strings.CutPrefix(value, RefPrefix)
+	if !ok || name == "" {
+		return "", false
+	}
+	return name, true
+}
+
+// ErrPassphrase is returned when the secrets can't be decrypted
+var ErrPassphrase = errors.New("wrong passphrase, or the secrets file is corrupted")
+
+const formatVersion = 1
+
+// scrypt parameters, recommended for interactive logins
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+
+	saltSize = 16
+)
+
+// file is the JSON content of a secrets file
+type file struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// additionalData authenticates the header of the file with the ciphertext, so its
+// parameters can't be changed without the passphrase
+func (f file) additionalData() []byte {
+	return fmt.Appendf(nil, "urlquery-cli secrets v%d kdf=%s n=%d r=%d p=%d salt=%x", f.Version, f.KDF, f.N, f.R, f.P, f.Salt)
+}
+
+// Store holds the decrypted secrets of a file
+type Store struct {
+	path       string
+	passphrase []byte
+	values     map[string]string
+}
+
+// Exists reports if the secrets file exists
+func Exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Open decrypts the secrets file. A missing file is empty, and is created with the
+// passphrase when saved.
+func Open(path string, passphrase []byte) (*Store, error) {
+	s := &Store{path: path, passphrase: passphrase, values: map[string]string{}}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid secrets file %s: %w", path, err)
+	}
+	if f.Version != formatVersion || f.KDF != "scrypt" {
+		return nil, fmt.Errorf("unsupported secrets file %s (version %d, kdf %s)", path, f.Version, f.KDF)
+	}
+	// Don't let a crafted file use a lot of memory or time
+	if f.N > 1<<20 || f.R*f.P > 64 {
+		return nil, fmt.Errorf("unsupported secrets file %s: scrypt parameters too large", path)
+	}
+	if len(f.Salt) < saltSize {
+		return nil, fmt.Errorf("invalid secrets file %s: salt too short", path)
+	}
+
+	aead, err := newAEAD(passphrase, f.Salt, f.N, f.R, f.P)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Nonce) != aead.NonceSize() {
+		return nil, ErrPassphrase
+	}
+	plaintext, err := aead.Open(nil, f.Nonce, f.Ciphertext, f.additionalData())
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	if err := json.Unmarshal(plaintext, &s.values); err != nil {
+		return nil, ErrPassphrase
+	}
+	return s, nil
+}
+
+// Get returns a secret by name
+func (s *Store) Get(name string) (string, bool) {
+	v, ok := s.values[name]
+	return v, ok
+}
+
+// Set sets a secret
+func (s *Store) Set(name, value string) {
+	s.values[name] = value
+}
+
+// Delete removes a secret, and reports if it existed
+func (s *Store) Delete(name string) bool {
+	_, ok := s.values[name]
+	delete(s.values, name)
+	return ok
+}
+
+// Save encrypts the secrets with a new salt and nonce, and writes the file atomically.
+// The file is only readable by its owner.
+func (s *Store) Save() error {
+	plaintext, err := json.Marshal(s.values)
+	if err != nil {
+		return err
+	}
+
+	f := file{
+		Version: formatVersion,
+		KDF:     "scrypt",
+		N:       scryptN,
+		R:       scryptR,
+		P:       scryptP,
+		Salt:    make([]byte, saltSize),
+	}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	aead, err := newAEAD(s.passphrase, f.Salt, f.N, f.R, f.P)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Ciphertext = aead.Seal(nil, f.Nonce, plaintext, f.additionalData())
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".secrets-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+func newAEAD(passphrase, salt []byte, n, r, p int) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, n, r, p, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid secrets file parameters: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,110 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets")
+
+	s, err := Open(path, []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Set("apikey", "abc123")
+	s.Set("team.apikey", "def456")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("abc123")) {
+		t.Error("secret stored in cleartext")
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	s, err = Open(path, []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := s.Get("team.apikey"); !ok || v != "def456" {
+		t.Errorf("Get = %q, %v", v, ok)
+	}
+
+	if _, err := Open(path, []byte("wrong")); !errors.Is(err, ErrPassphrase) {
+		t.Errorf("wrong passphrase: err = %v", err)
+	}
+}
+
+func TestOpenTampered(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets")
+	s, _ := Open(path, []byte("correct horse"))
+	s.Set("apikey", "abc123")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+
+	tests := map[string]func(f *file){
+		"version": func(f *file) { f.Version = 2 },
+		"params":  func(f *file) { f.P = 2 },
+		"salt":    func(f *file) { f.Salt[0] ^= 1 },
+		"no salt": func(f *file) { f.Salt = nil },
+		"short":   func(f *file) { f.Salt = f.Salt[:8] },
+	}
+	for name, tamper := range tests {
+		t.Run(name, func(t *testing.T) {
+			var f file
+			if err := json.Unmarshal(data, &f); err != nil {
+				t.Fatal(err)
+			}
+			tamper(&f)
+			tampered, _ := json.Marshal(f)
+			os.WriteFile(path, tampered, 0600)
+
+			if _, err := Open(path, []byte("correct horse")); err == nil {
+				t.Error("tampered file opened")
+			}
+		})
+	}
+
+	// The header is authenticated even where it doesn't change the key
+	var f file
+	json.Unmarshal(data, &f)
+	aead, _ := newAEAD([]byte("correct horse"), f.Salt, f.N, f.R, f.P)
+	plaintext, err := aead.Open(nil, f.Nonce, f.Ciphertext, f.additionalData())
+	if err != nil || string(plaintext) != `{"apikey":"abc123"}` {
+		t.Fatalf("Open() = %s, %v", plaintext, err)
+	}
+	if _, err := aead.Open(nil, f.Nonce, f.Ciphertext, nil); err == nil {
+		t.Error("ciphertext opened without the header")
+	}
+}
+
+func TestRef(t *testing.T) {
+	tests := []struct {
+		value string
+		name  string
+		ok    bool
+	}{
+		{"secret:apikey", "apikey", true},
+		{"secret:", "", false},
+		{"abc123", "", false},
+	}
+	for _, tt := range tests {
+		name, ok := Ref(tt.value)
+		if name != tt.name || ok != tt.ok {
+			t.Errorf("Ref(%q) = %q, %v", tt.value, name, ok)
+		}
+	}
+}
@@ -0,0 +1,477 @@
+// Package stix converts urlquery reports to STIX 2.1 bundles.
+//
+// Observables (SCOs) use the deterministic identifiers defined by the STIX 2.1
+// specification, and all other objects use UUIDv5 identifiers derived from the
+// report, so exporting the same report twice gives the same objects.
+package stix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/ioc"
+)
+
+const SpecVersion = "2.1"
+
+// Namespace for the deterministic identifiers of STIX Cyber-observable Objects
+var scoNamespace = uuid.MustParse("00abedb4-aa42-466c-9c01-fed23315a9b7")
+
+// Namespace for the identifiers of the other objects created by urlquery-cli
+var namespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://urlquery.net"))
+
+type Bundle struct {
+	Type    string    `json:"type"`
+	ID      string    `json:"id"`
+	Objects []*Object `json:"objects"`
+}
+
+// Object holds the properties of every object type used in the bundles
+type Object struct {
+	Type         string `json:"type"`
+	SpecVersion  string `json:"spec_version"`
+	ID           string `json:"id"`
+	Created      string `json:"created,omitempty"`
+	Modified     string `json:"modified,omitempty"`
+	CreatedByRef string `json:"created_by_ref,omitempty"`
+
+	Name          string `json:"name,omitempty"`
+	Description   string `json:"description,omitempty"`
+	IdentityClass string `json:"identity_class,omitempty"`
+
+	// Cyber-observables
+	Value             string            `json:"value,omitempty"`
+	Number            int               `json:"number,omitempty"`
+	ResolvesToRefs    []string          `json:"resolves_to_refs,omitempty"`
+	BelongsToRefs     []string          `json:"belongs_to_refs,omitempty"`
+	Hashes            map[string]string `json:"hashes,omitempty"`
+	Size              int               `json:"size,omitempty"`
+	MimeType          string            `json:"mime_type,omitempty"`
+	Subject           string            `json:"subject,omitempty"`
+	Issuer            string            `json:"issuer,omitempty"`
+	ValidityNotBefore string            `json:"validity_not_before,omitempty"`
+	ValidityNotAfter  string            `json:"validity_not_after,omitempty"`
+
+	// Indicator
+	IndicatorTypes []string `json:"indicator_types,omitempty"`
+	Pattern        string   `json:"pattern,omitempty"`
+	PatternType    string   `json:"pattern_type,omitempty"`
+	ValidFrom      string   `json:"valid_from,omitempty"`
+	Labels         []string `json:"labels,omitempty"`
+
+	// Observed data
+	FirstObserved  string   `json:"first_observed,omitempty"`
+	LastObserved   string   `json:"last_observed,omitempty"`
+	NumberObserved int      `json:"number_observed,omitempty"`
+	ObjectRefs     []string `json:"object_refs,omitempty"`
+
+	// Sighting
+	SightingOfRef    string   `json:"sighting_of_ref,omitempty"`
+	ObservedDataRefs []string `json:"observed_data_refs,omitempty"`
+	WhereSightedRefs []string `json:"where_sighted_refs,omitempty"`
+	FirstSeen        string   `json:"first_seen,omitempty"`
+	LastSeen         string   `json:"last_seen,omitempty"`
+	Count            int      `json:"count,omitempty"`
+
+	// Relationship
+	RelationshipType string `json:"relationship_type,omitempty"`
+	SourceRef        string `json:"source_ref,omitempty"`
+	TargetRef        string `json:"target_ref,omitempty"`
+
+	ExternalReferences []ExternalReference `json:"external_references,omitempty"`
+}
+
+type ExternalReference struct {
+	SourceName string `json:"source_name"`
+	URL        string `json:"url,omitempty"`
+	ExternalID string `json:"external_id,omitempty"`
+}
+
+// builder collects the unique objects of a bundle
+type builder struct {
+	report    *api.Report
+	timestamp string
+	identity  string
+	objects   []*Object
+	index     map[string]*Object
+	observed  []string
+}
+
+func (b *builder) add(o *Object) *Object {
+	if existing, ok := b.index[o.ID]; ok {
+		// The same file or certificate seen with more hashes
+		for name, value := range o.Hashes {
+			if _, ok := existing.Hashes[name]; !ok {
+				existing.Hashes[name] = value
+			}
+		}
+		return existing
+	}
+	o.SpecVersion = SpecVersion
+	b.index[o.ID] = o
+	b.objects = append(b.objects, o)
+
+	if isObservable(o.Type) {
+		b.observed = append(b.observed, o.ID)
+	}
+	return o
+}
+
+// sdo fills the common properties of domain and relationship objects
+func (b *builder) sdo(o *Object) *Object {
+	o.Created = b.timestamp
+	o.Modified = b.timestamp
+	o.CreatedByRef = b.identity
+	return b.add(o)
+}
+
+// FromReport builds a bundle with the observables of a report, an indicator for every
+// alert with a sighting, and relationships between the requests (who requested what).
+func FromReport(r *api.Report) *Bundle {
+	b := &builder{
+		report:    r,
+		timestamp: timestamp(r.Date),
+		index:     make(map[string]*Object),
+	}
+
+	identity := b.add(&Object{
+		Type:          "identity",
+		ID:            objectID("identity", "urlquery.net"),
+		Created:       "2025-01-01T00:00:00.000Z",
+		Modified:      "2025-01-01T00:00:00.000Z",
+		Name:          "urlquery.net",
+		IdentityClass: "organization",
+	})
+	b.identity = identity.ID
+
+	// Observables
+	submitted := b.url(r.Url, r.Ip)
+	b.url(r.Final.Url, api.IP{})
+	for _, s := range r.Summary {
+		b.domain(s.Fqdn, s.Ip)
+	}
+
+	urls := make(map[string]string) // URL -> url object id
+	for _, tx := range r.HttpTransactions {
+		if id := b.url(tx.Url, tx.Ip); id != "" {
+			urls[ioc.FullURL(tx.Url)] = id
+		}
+		c := tx.Response.Content
+		b.file(c.Md5, c.Sha1, c.Sha256, c.Sha512, c.Size, c.MimeType)
+		if tx.SecurityInfo != nil {
+			b.certificate(tx.SecurityInfo.Cert)
+		}
+		for _, alert := range tx.Alerts.IDSAlerts {
+			b.ip(alert.IpDst)
+		}
+	}
+	for _, sensor := range r.Sensors.NetworkSensors {
+		for _, alert := range sensor.Alerts {
+			b.ip(alert.IpDst)
+		}
+	}
+	for _, f := range r.FileDetections {
+		b.url(f.Url, f.Ip)
+		b.file(f.Md5, f.Sha1, f.Sha256, f.Sha512, f.Size, "")
+	}
+
+	// object_refs is required, so there is no observed-data without observables
+	observedID := ""
+	if len(b.observed) > 0 {
+		observedID = b.sdo(&Object{
+			Type:           "observed-data",
+			ID:             objectID("observed-data", r.ID),
+			FirstObserved:  b.timestamp,
+			LastObserved:   b.timestamp,
+			NumberObserved: 1,
+			ObjectRefs:     append([]string{}, b.observed...),
+			ExternalReferences: []ExternalReference{{
+				SourceName: "urlquery",
+				URL:        "https://urlquery.net/report/" + r.ID,
+				ExternalID: r.ID,
+			}},
+		}).ID
+	}
+
+	// Indicators and sightings from the alerts
+	for _, alert := range r.Sensors.UrlQueryAlerts {
+		if submitted != "" {
+			b.indicator(alert.Alert, "urlquery", urlPattern(r.Url), observedID)
+		}
+	}
+	for _, tx := range r.HttpTransactions {
+		for _, alert := range tx.Alerts.UrlqueryAlerts {
+			b.indicator(alert.Alert, "urlquery", urlPattern(tx.Url), observedID)
+		}
+		for _, alert := range tx.Alerts.IDSAlerts {
+			pattern := urlPattern(tx.Url)
+			if alert.IpDst.Addr != "" {
+				pattern = ipPattern(alert.IpDst.Addr)
+			}
+			b.indicator(alert.Alert, "ids", pattern, observedID)
+		}
+		for _, alert := range tx.Alerts.AnalyzerAlerts {
+			pattern := urlPattern(tx.Url)
+			if sha256 := tx.Response.Content.Sha256; sha256 != "" {
+				pattern = hashPattern(sha256)
+			}
+			b.indicator(alert.Alert, "analyzer/"+alert.SensorName, pattern, observedID)
+		}
+	}
+	for _, sensor := range r.Sensors.NetworkSensors {
+		for _, alert := range sensor.Alerts {
+			if alert.IpDst.Addr != "" {
+				b.indicator(alert.Alert, "ids", ipPattern(alert.IpDst.Addr), observedID)
+			}
+		}
+	}
+	for _, f := range r.FileDetections {
+		for _, alert := range f.Alerts.AnalyzerAlerts {
+			if f.Sha256 != "" {
+				b.indicator(alert.Alert, "analyzer/"+alert.SensorName, hashPattern(f.Sha256), observedID)
+			}
+		}
+	}
+
+	// Relationships following the RequestedBy chain
+	for _, tx := range r.HttpTransactions {
+		target, ok := urls[ioc.FullURL(tx.Url)]
+		source, found := urls[tx.RequestedBy]
+		if !ok || !found || source == target {
+			continue
+		}
+		b.sdo(&Object{
+			Type:             "relationship",
+			ID:               objectID("relationship", r.ID, source, target),
+			RelationshipType: "related-to",
+			Description:      "requested",
+			SourceRef:        source,
+			TargetRef:        target,
+		})
+	}
+
+	return &Bundle{
+		Type:    "bundle",
+		ID:      objectID("bundle", r.ID),
+		Objects: b.objects,
+	}
+}
+
+// url adds url, domain-name, ip and autonomous-system objects. Returns the url object id.
+func (b *builder) url(u api.URL, ip api.IP) string {
+	if u.Addr == "" {
+		return ""
+	}
+	value := ioc.FullURL(u)
+	o := b.add(&Object{Type: "url", ID: scoID("url", map[string]any{"value": value}), Value: value})
+	b.domain(u.Fqdn, ip)
+	return o.ID
+}
+
+func (b *builder) domain(fqdn string, ip api.IP) {
+	fqdn = strings.ToLower(fqdn)
+	if fqdn == "" {
+		return
+	}
+
+	o := b.add(&Object{Type: "domain-name", ID: scoID("domain-name", map[string]any{"value": fqdn}), Value: fqdn})
+	if ipID := b.ip(ip); ipID != "" && !slices.Contains(o.ResolvesToRefs, ipID) {
+		o.ResolvesToRefs = append(o.ResolvesToRefs, ipID)
+	}
+}
+
+func (b *builder) ip(ip api.IP) string {
+	parsed := net.ParseIP(ip.Addr)
+	if parsed == nil {
+		return ""
+	}
+
+	typ := "ipv4-addr"
+	if parsed.To4() == nil {
+		typ = "ipv6-addr"
+	}
+	o := b.add(&Object{Type: typ, ID: scoID(typ, map[string]any{"value": ip.Addr}), Value: ip.Addr})
+
+	if ip.ASN > 0 {
+		as := b.add(&Object{
+			Type:   "autonomous-system",
+			ID:     scoID("autonomous-system", map[string]any{"number": ip.ASN}),
+			Number: ip.ASN,
+			Name:   ip.AS,
+		})
+		if !slices.Contains(o.BelongsToRefs, as.ID) {
+			o.BelongsToRefs = append(o.BelongsToRefs, as.ID)
+		}
+	}
+	return o.ID
+}
+
+func (b *builder) file(md5, sha1, sha256, sha512 string, size int, mime string) {
+	hashes := map[string]string{}
+	for name, value := range map[string]string{"MD5": md5, "SHA-1": sha1, "SHA-256": sha256, "SHA-512": sha512} {
+		if value != "" {
+			hashes[name] = strings.ToLower(value)
+		}
+	}
+	if len(hashes) == 0 {
+		return
+	}
+
+	o := b.add(&Object{Type: "file", ID: scoID("file", map[string]any{"hashes": idHash(hashes)}), Hashes: hashes, Size: size})
+	if o.MimeType == "" {
+		o.MimeType = mime
+	}
+}
+
+func (b *builder) certificate(cert api.CertInfo) {
+	hashes := map[string]string{}
+	if fp := normalizeFingerprint(cert.Fingerprint.Sha1); fp != "" {
+		hashes["SHA-1"] = fp
+	}
+	if fp := normalizeFingerprint(cert.Fingerprint.Sha256); fp != "" {
+		hashes["SHA-256"] = fp
+	}
+	if len(hashes) == 0 {
+		return
+	}
+
+	b.add(&Object{
+		Type:              "x509-certificate",
+		ID:                scoID("x509-certificate", map[string]any{"hashes": idHash(hashes)}),
+		Hashes:            hashes,
+		Subject:           distinguishedName(cert.Subject.CommonName, cert.Subject.Organization),
+		Issuer:            distinguishedName(cert.Issuer.CommonName, cert.Issuer.Organization),
+		ValidityNotBefore: optionalTimestamp(cert.Validity.Start),
+		ValidityNotAfter:  optionalTimestamp(cert.Validity.End),
+	})
+}
+
+// indicator adds an indicator for an alert, and a sighting of it in the report
+func (b *builder) indicator(alert, sensor, pattern, observedID string) {
+	if alert == "" || pattern == "" {
+		return
+	}
+
+	ind := b.sdo(&Object{
+		Type:           "indicator",
+		ID:             objectID("indicator", b.report.ID, sensor, alert, pattern),
+		Name:           alert,
+		Description:    fmt.Sprintf("%s alert in urlquery.net report %s", sensor, b.report.ID),
+		IndicatorTypes: []string{"malicious-activity"},
+		Pattern:        pattern,
+		PatternType:    "stix",
+		ValidFrom:      b.timestamp,
+		Labels:         []string{sensor},
+	})
+
+	sighting := &Object{
+		Type:             "sighting",
+		ID:               objectID("sighting", ind.ID),
+		SightingOfRef:    ind.ID,
+		WhereSightedRefs: []string{b.identity},
+		FirstSeen:        b.timestamp,
+		LastSeen:         b.timestamp,
+		Count:            1,
+	}
+	if observedID != "" {
+		sighting.ObservedDataRefs = []string{observedID}
+	}
+	b.sdo(sighting)
+}
+
+func urlPattern(u api.URL) string {
+	if u.Addr == "" {
+		return ""
+	}
+	return fmt.Sprintf("[url:value = '%s']", escape(ioc.FullURL(u)))
+}
+
+func ipPattern(ip string) string {
+	typ := "ipv4-addr"
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		typ = "ipv6-addr"
+	}
+	return fmt.Sprintf("[%s:value = '%s']", typ, escape(ip))
+}
+
+func hashPattern(sha256 string) string {
+	return fmt.Sprintf("[file:hashes.'SHA-256' = '%s']", escape(strings.ToLower(sha256)))
+}
+
+// escape quotes a string literal in a STIX pattern
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// scoID returns the deterministic identifier of an observable, a UUIDv5 of its
+// ID contributing properties serialized as canonical JSON (sorted keys).
+func scoID(typ string, properties map[string]any) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(properties)
+	return typ + "--" + uuid.NewSHA1(scoNamespace, bytes.TrimSuffix(buf.Bytes(), []byte("\n"))).String()
+}
+
+// idHash returns the hash of a file or certificate used in its identifier. STIX 2.1
+// uses a single hash, the first found of MD5, SHA-1, SHA-256 and SHA-512, so the
+// same file has the same identifier whichever other hashes are known.
+func idHash(hashes map[string]string) map[string]string {
+	for _, name := range []string{"MD5", "SHA-1", "SHA-256", "SHA-512"} {
+		if value, ok := hashes[name]; ok {
+			return map[string]string{name: value}
+		}
+	}
+	return hashes
+}
+
+// objectID returns an identifier derived from the given names
+func objectID(typ string, names ...string) string {
+	return typ + "--" + uuid.NewSHA1(namespace, []byte(typ+"|"+strings.Join(names, "|"))).String()
+}
+
+func isObservable(typ string) bool {
+	switch typ {
+	case "url", "domain-name", "ipv4-addr", "ipv6-addr", "autonomous-system", "file", "x509-certificate":
+		return true
+	}
+	return false
+}
+
+// timestamp formats a report date as a STIX timestamp (UTC, millisecond precision)
+func timestamp(date string) string {
+	t, err := time.Parse(time.RFC3339Nano, date)
+	if err != nil {
+		t = time.Unix(0, 0)
+	}
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+func optionalTimestamp(date string) string {
+	if _, err := time.Parse(time.RFC3339Nano, date); err != nil {
+		return ""
+	}
+	return timestamp(date)
+}
+
+func normalizeFingerprint(fp string) string {
+	return strings.ToLower(strings.ReplaceAll(fp, ":", ""))
+}
+
+func distinguishedName(cn, org string) string {
+	var parts []string
+	if cn != "" {
+		parts = append(parts, "CN="+cn)
+	}
+	if org != "" {
+		parts = append(parts, "O="+org)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,151 @@
+package stix
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/urlquery/urlquery-cli/internal/api"
+	"github.com/urlquery/urlquery-cli/internal/api/apitest"
+)
+
+func objectsOfType(b *Bundle, typ string) []*Object {
+	var out []*Object
+	for _, o := range b.Objects {
+		if o.Type == typ {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+func TestFromReport(t *testing.T) {
+	b := FromReport(apitest.Report())
+
+	counts := map[string]int{}
+	ids := map[string]bool{}
+	for _, o := range b.Objects {
+		counts[o.Type]++
+		if ids[o.ID] {
+			t.Errorf("Duplicate object %s", o.ID)
+		}
+		ids[o.ID] = true
+		if !strings.HasPrefix(o.ID, o.Type+"--") || o.SpecVersion != "2.1" {
+			t.Errorf("Invalid object %+v", o)
+		}
+	}
+
+	expected := map[string]int{
+		"identity":          1,
+		"url":               2,
+		"domain-name":       2,
+		"ipv4-addr":         2,
+		"autonomous-system": 2,
+		"file":              3,
+		"x509-certificate":  1,
+		"observed-data":     1,
+		"indicator":         5,
+		"sighting":          5,
+		"relationship":      1,
+	}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("Unexpected objects %v", counts)
+	}
+
+	// References must point to objects in the bundle
+	for _, o := range b.Objects {
+		refs := append([]string{o.SightingOfRef, o.SourceRef, o.TargetRef}, o.ObjectRefs...)
+		refs = append(refs, o.ResolvesToRefs...)
+		refs = append(refs, o.BelongsToRefs...)
+		for _, ref := range refs {
+			if ref != "" && !ids[ref] {
+				t.Errorf("%s references missing object %s", o.ID, ref)
+			}
+		}
+	}
+
+	patterns := map[string]bool{}
+	for _, ind := range objectsOfType(b, "indicator") {
+		patterns[ind.Pattern] = true
+		if ind.ValidFrom != "2025-06-02T10:00:00.000Z" {
+			t.Errorf("Unexpected valid_from %s", ind.ValidFrom)
+		}
+	}
+	for _, p := range []string{
+		"[url:value = 'https://login.example.com/signin?next=%2Fhome&x=1']",
+		"[file:hashes.'SHA-256' = 'e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855']",
+		"[ipv4-addr:value = '198.51.100.7']",
+	} {
+		if !patterns[p] {
+			t.Errorf("Missing indicator pattern %s in %v", p, patterns)
+		}
+	}
+
+	rel := objectsOfType(b, "relationship")[0]
+	url := func(id string) string {
+		for _, o := range b.Objects {
+			if o.ID == id {
+				return o.Value
+			}
+		}
+		return ""
+	}
+	if url(rel.SourceRef) != "https://login.example.com/signin?next=%2Fhome&x=1" || url(rel.TargetRef) != "https://cdn.example.net/kit.js" {
+		t.Errorf("Unexpected relationship %+v", rel)
+	}
+}
+
+func TestDeterministicIDs(t *testing.T) {
+	a, _ := json.Marshal(FromReport(apitest.Report()))
+	b, _ := json.Marshal(FromReport(apitest.Report()))
+	if string(a) != string(b) {
+		t.Errorf("Exports differ")
+	}
+
+	// UUIDv5 of {"value":"https://example.com/"} in the STIX namespace
+	if id := scoID("url", map[string]any{"value": "https://example.com/"}); id != "url--be22e93a-5e33-5678-b19f-8b4ea06df0bd" {
+		t.Errorf("Unexpected id %s", id)
+	}
+}
+
+func TestPatternEscaping(t *testing.T) {
+	p := urlPattern(api.URL{Schema: "http", Addr: `example.com/it's\here`})
+	if p != `[url:value = 'http://example.com/it\'s\\here']` {
+		t.Errorf("Unexpected pattern %s", p)
+	}
+}
+
+func TestFileIDs(t *testing.T) {
+	fileID := func(files ...api.FileObservation) (string, map[string]string) {
+		r := &api.Report{}
+		r.ID = "r1"
+		r.FileDetections = files
+		objects := objectsOfType(FromReport(r), "file")
+		if len(objects) != 1 {
+			t.Fatalf("%d file objects", len(objects))
+		}
+		return objects[0].ID, objects[0].Hashes
+	}
+
+	// The identifier uses a single hash, SHA-256 before SHA-512
+	a, _ := fileID(api.FileObservation{Sha256: "ABCDEF", Sha512: "123456"})
+	b, _ := fileID(api.FileObservation{Sha256: "abcdef"})
+	if a != b {
+		t.Errorf("Same SHA-256, different IDs %s and %s", a, b)
+	}
+
+	// The hashes of the same file are merged
+	_, hashes := fileID(api.FileObservation{Sha256: "abcdef"}, api.FileObservation{Sha256: "abcdef", Sha512: "123456"})
+	if hashes["SHA-512"] != "123456" {
+		t.Errorf("Hashes not merged: %v", hashes)
+	}
+}
+
+func TestNoObservables(t *testing.T) {
+	r := &api.Report{}
+	r.ID = "r1"
+	if o := objectsOfType(FromReport(r), "observed-data"); len(o) != 0 {
+		t.Errorf("observed-data without observables: %+v", o[0])
+	}
+}